@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBatcherClosed is returned by Batcher.Add and Batcher.Close after the Batcher has been closed.
+var ErrBatcherClosed = errors.New("cache: batcher is closed")
+
+// Batcher buffers values in a RollingCache and passes them to a flush function in
+// batches, either when size values have been added or when maxDelay has elapsed.
+// Batches never contain more than size values, and flushes run one at a time on a
+// background goroutine. The flush function must not retain a batch after it returns,
+// because its backing array is recycled for later batches.
+type Batcher[V any] struct {
+	cache    *RollingCache[V]
+	size     int
+	maxDelay time.Duration
+	flush    func(ctx context.Context, batch []V) error
+	onError  func(err error)
+
+	mu      sync.RWMutex // guards closed and flushed against concurrent Add and Close
+	closed  bool
+	flushed bool          // the final flush of Close has started
+	full    chan struct{} // signals that size values are buffered
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewBatcher creates a new Batcher and starts its background goroutine.
+// flush is called with each batch; errors returned by background flushes are passed
+// to onError, which may be nil. Close must be called to stop the Batcher.
+// It panics if size or maxDelay is not positive.
+func NewBatcher[V any](size int, maxDelay time.Duration, flush func(ctx context.Context, batch []V) error, onError func(err error)) *Batcher[V] {
+	if size <= 0 {
+		panic("cache: non-positive size for NewBatcher")
+	}
+	if maxDelay <= 0 {
+		panic("cache: non-positive maxDelay for NewBatcher")
+	}
+	b := &Batcher[V]{
+		cache:    NewRollingCache[V](size),
+		size:     size,
+		maxDelay: maxDelay,
+		flush:    flush,
+		onError:  onError,
+		full:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Add buffers a value for the next batch.
+func (b *Batcher[V]) Add(value V) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrBatcherClosed
+	}
+	b.cache.Append(value)
+	if b.cache.Size() >= b.size {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close stops the background goroutine, waiting for an in-progress flush, and then
+// flushes the remaining values with ctx. It returns the errors of the final flush.
+//
+// If ctx is done before the background goroutine stops, Close returns ctx.Err() and
+// the remaining values stay buffered; Add is rejected from then on, and Close can be
+// called again to wait for the background goroutine and flush them.
+// Once the final flush has started, Close returns ErrBatcherClosed.
+func (b *Batcher[V]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.flushed {
+		b.mu.Unlock()
+		return ErrBatcherClosed
+	}
+	if !b.closed {
+		b.closed = true
+		close(b.stop)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	b.mu.Lock()
+	if b.flushed {
+		// A concurrent Close got here first.
+		b.mu.Unlock()
+		return ErrBatcherClosed
+	}
+	b.flushed = true
+	b.mu.Unlock()
+	return b.flushAll(ctx)
+}
+
+func (b *Batcher[V]) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.maxDelay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-b.full:
+		case <-ticker.C:
+		}
+		if err := b.flushAll(context.Background()); err != nil && b.onError != nil {
+			b.onError(err)
+		}
+	}
+}
+
+// flushAll rotates the buffered values and flushes them in batches of at most size values.
+func (b *Batcher[V]) flushAll(ctx context.Context) error {
+	var errs []error
+	items := b.cache.Rotate()
+	for batch := items; len(batch) > 0; {
+		n := min(b.size, len(batch))
+		if err := b.flush(ctx, batch[:n]); err != nil {
+			errs = append(errs, err)
+		}
+		batch = batch[n:]
+	}
+	b.cache.Release(items)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,191 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/catatsuy/cache"
+)
+
+// recorder collects the batches passed to a Batcher flush function.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (r *recorder) flush(ctx context.Context, batch []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, slices.Clone(batch))
+	return nil
+}
+
+func (r *recorder) get() [][]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.batches)
+}
+
+func TestBatcher_FlushOnSize(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var r recorder
+		b := cache.NewBatcher(3, time.Hour, r.flush, nil)
+		defer b.Close(context.Background())
+
+		for i := range 3 {
+			b.Add(i)
+		}
+		synctest.Wait()
+
+		if got := r.get(); len(got) != 1 || !slices.Equal(got[0], []int{0, 1, 2}) {
+			t.Errorf("Expected one batch [0 1 2], but got %v", got)
+		}
+	})
+}
+
+func TestBatcher_FlushOnDelay(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var r recorder
+		b := cache.NewBatcher(10, time.Second, r.flush, nil)
+		defer b.Close(context.Background())
+
+		b.Add(1)
+		b.Add(2)
+		synctest.Wait()
+		if got := r.get(); len(got) != 0 {
+			t.Errorf("Expected no batch before the delay, but got %v", got)
+		}
+
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if got := r.get(); len(got) != 1 || !slices.Equal(got[0], []int{1, 2}) {
+			t.Errorf("Expected one batch [1 2], but got %v", got)
+		}
+	})
+}
+
+func TestBatcher_Close(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var r recorder
+		b := cache.NewBatcher(2, time.Hour, r.flush, nil)
+
+		// Add more values than fit in a batch without letting the background goroutine run
+		b.Add(1)
+		b.Add(2)
+		b.Add(3)
+		if err := b.Close(context.Background()); err != nil {
+			t.Errorf("Close error: %v", err)
+		}
+
+		var all []int
+		for _, batch := range r.get() {
+			if len(batch) > 2 {
+				t.Errorf("Expected batches of at most 2 values, but got %v", batch)
+			}
+			all = append(all, batch...)
+		}
+		if !slices.Equal(all, []int{1, 2, 3}) {
+			t.Errorf("Expected all values to be flushed, but got %v", all)
+		}
+
+		if err := b.Add(4); !errors.Is(err, cache.ErrBatcherClosed) {
+			t.Errorf("Expected ErrBatcherClosed, but got %v", err)
+		}
+		if err := b.Close(context.Background()); !errors.Is(err, cache.ErrBatcherClosed) {
+			t.Errorf("Expected ErrBatcherClosed, but got %v", err)
+		}
+	})
+}
+
+func TestBatcher_Errors(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		flushErr := errors.New("flush error")
+		flush := func(ctx context.Context, batch []int) error {
+			return flushErr
+		}
+		var mu sync.Mutex
+		var reported []error
+		b := cache.NewBatcher(1, time.Hour, flush, func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, err)
+		})
+
+		b.Add(1)
+		synctest.Wait()
+		mu.Lock()
+		if len(reported) != 1 || !errors.Is(reported[0], flushErr) {
+			t.Errorf("Expected flush error to be reported, but got %v", reported)
+		}
+		mu.Unlock()
+
+		if err := b.Close(context.Background()); err != nil {
+			t.Errorf("Expected nothing left to flush on Close, but got %v", err)
+		}
+	})
+}
+
+func TestBatcher_CloseTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var r recorder
+		release := make(chan struct{})
+		flush := func(ctx context.Context, batch []int) error {
+			if batch[0] == 1 {
+				<-release // hold up the background flush
+			}
+			return r.flush(ctx, batch)
+		}
+		b := cache.NewBatcher(1, time.Hour, flush, nil)
+
+		b.Add(1)
+		synctest.Wait()
+		b.Add(2)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected DeadlineExceeded, but got %v", err)
+		}
+		if err := b.Add(3); !errors.Is(err, cache.ErrBatcherClosed) {
+			t.Errorf("Expected ErrBatcherClosed after a timed out Close, but got %v", err)
+		}
+
+		close(release)
+		if err := b.Close(context.Background()); err != nil {
+			t.Errorf("Expected the retried Close to flush, but got %v", err)
+		}
+		if got := r.get(); len(got) != 2 || !slices.Equal(got[1], []int{2}) {
+			t.Errorf("Expected batches [1] and [2], but got %v", got)
+		}
+		if err := b.Close(context.Background()); !errors.Is(err, cache.ErrBatcherClosed) {
+			t.Errorf("Expected ErrBatcherClosed, but got %v", err)
+		}
+	})
+}
+
+func TestBatcher_InvalidArguments(t *testing.T) {
+	flush := func(ctx context.Context, batch []int) error { return nil }
+	for _, tt := range []struct {
+		size     int
+		maxDelay time.Duration
+	}{
+		{0, time.Second},
+		{-1, time.Second},
+		{1, 0},
+		{1, -time.Second},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected NewBatcher(%d, %v) to panic", tt.size, tt.maxDelay)
+				}
+			}()
+			cache.NewBatcher(tt.size, tt.maxDelay, flush, nil)
+		}()
+	}
+}
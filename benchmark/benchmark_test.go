@@ -1,6 +1,7 @@
 package benchmark_test
 
 import (
+	"fmt"
 	"strconv"
 	"testing"
 
@@ -30,6 +31,38 @@ func BenchmarkSingleflight(b *testing.B) {
 	}
 }
 
+// BenchmarkSingleflightKeys compares formatting integer keys into strings, as
+// cache-fill paths must do with SingleflightGroup, against using them directly
+// with SingleflightGroupKeyed.
+func BenchmarkSingleflightKeys(b *testing.B) {
+	for _, keys := range []int{1, 10} {
+		b.Run("sprintf/keys="+strconv.Itoa(keys), func(b *testing.B) {
+			sf := cache.NewSingleflightGroup[int]()
+			runKeys(b, keys, func(key int) {
+				sf.Do(fmt.Sprintf("cacheGet_%d", key), func() (int, error) { return key, nil })
+			})
+		})
+		b.Run("keyed/keys="+strconv.Itoa(keys), func(b *testing.B) {
+			sf := cache.NewSingleflightGroupKeyed[int, int]()
+			runKeys(b, keys, func(key int) {
+				sf.Do(key, func() (int, error) { return key, nil })
+			})
+		})
+	}
+}
+
+func runKeys(b *testing.B, keyCount int, do func(key int)) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			do(i % keyCount)
+			i++
+		}
+	})
+}
+
 func runStd(b *testing.B, sg *singleflight.Group, keyCount int) {
 	b.ReportAllocs()
 	keys := genKeys(keyCount)
@@ -0,0 +1,34 @@
+package benchmark_test
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/catatsuy/cache"
+)
+
+// BenchmarkLockManagerHighCardinality locks a new key on every operation,
+// as when locking on request IDs.
+func BenchmarkLockManagerHighCardinality(b *testing.B) {
+	b.Run("keyed", func(b *testing.B) {
+		lm := cache.NewLockManager[uint64]()
+		runLockManager(b, lm.Lock, lm.Unlock)
+	})
+	b.Run("striped", func(b *testing.B) {
+		lm := cache.NewStripedLockManager[uint64](1024)
+		runLockManager(b, lm.Lock, lm.Unlock)
+	})
+}
+
+func runLockManager(b *testing.B, lock, unlock func(uint64)) {
+	b.ReportAllocs()
+	var next atomic.Uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			id := next.Add(1)
+			lock(id)
+			unlock(id)
+		}
+	})
+}
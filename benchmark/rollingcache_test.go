@@ -0,0 +1,72 @@
+package benchmark_test
+
+import (
+	"testing"
+
+	"github.com/catatsuy/cache"
+)
+
+const rotateBatch = 64
+
+func BenchmarkRollingCacheRotate(b *testing.B) {
+	b.Run("rotate", func(b *testing.B) {
+		c := cache.NewRollingCache[int](rotateBatch)
+		b.ReportAllocs()
+		for range b.N {
+			for j := range rotateBatch {
+				c.Append(j)
+			}
+			_ = c.Rotate()
+		}
+	})
+	b.Run("release", func(b *testing.B) {
+		c := cache.NewRollingCache[int](rotateBatch)
+		b.ReportAllocs()
+		for range b.N {
+			for j := range rotateBatch {
+				c.Append(j)
+			}
+			c.Release(c.Rotate())
+		}
+	})
+	b.Run("rotate-into", func(b *testing.B) {
+		c := cache.NewRollingCache[int](rotateBatch)
+		buf := make([]int, 0, rotateBatch)
+		b.ReportAllocs()
+		for range b.N {
+			for j := range rotateBatch {
+				c.Append(j)
+			}
+			buf = c.RotateInto(buf)
+		}
+	})
+}
+
+func BenchmarkRollingCacheParallelAppend(b *testing.B) {
+	b.Run("mutex", func(b *testing.B) {
+		c := cache.NewRollingCache[int](1024)
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				c.Append(i)
+				if i++; i%1024 == 0 {
+					c.Release(c.Rotate())
+				}
+			}
+		})
+	})
+	b.Run("striped", func(b *testing.B) {
+		c := cache.NewStripedRollingCache[int](1024)
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				c.Append(i)
+				if i++; i%1024 == 0 {
+					c.Rotate()
+				}
+			}
+		})
+	})
+}
@@ -1,7 +1,11 @@
 package cache
 
 import (
+	"context"
+	"hash/maphash"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -286,6 +290,7 @@ type WriteHeavyCacheInteger[K comparable, V interface {
 }] struct {
 	sync.Mutex // WriteHeavyCacheInteger uses Mutex for write-heavy scenarios
 	items      map[K]V
+	topk       *topKTracker[K, V] // nil unless EnableTopK has been called
 }
 
 // ReadHeavyCacheInteger is a cache optimized for read-heavy operations for integer-like types.
@@ -295,6 +300,7 @@ type ReadHeavyCacheInteger[K comparable, V interface {
 }] struct {
 	sync.RWMutex // ReadHeavyCacheInteger uses RWMutex for read-heavy scenarios
 	items        map[K]V
+	topk         *topKTracker[K, V] // nil unless EnableTopK has been called
 }
 
 // NewWriteHeavyCacheInteger creates a new write-heavy cache for integer types
@@ -319,6 +325,9 @@ func NewReadHeavyCacheInteger[K comparable, V interface {
 func (c *WriteHeavyCacheInteger[K, V]) Set(key K, value V) {
 	c.Lock()
 	c.items[key] = value
+	if c.topk != nil {
+		c.topk.update(key, value)
+	}
 	c.Unlock()
 }
 
@@ -335,9 +344,11 @@ func (c *WriteHeavyCacheInteger[K, V]) Incr(key K, value V) {
 	c.Lock()
 	v, found := c.items[key]
 	if found {
-		c.items[key] = v + value
-	} else {
-		c.items[key] = value
+		value += v
+	}
+	c.items[key] = value
+	if c.topk != nil {
+		c.topk.update(key, value)
 	}
 	c.Unlock()
 }
@@ -347,12 +358,18 @@ func (c *WriteHeavyCacheInteger[K, V]) Delete(key K) {
 	c.Lock()
 	defer c.Unlock()
 	delete(c.items, key)
+	if c.topk != nil {
+		c.topk.remove(key)
+	}
 }
 
 // Clear removes all items from WriteHeavyCacheInteger.
 func (c *WriteHeavyCacheInteger[K, V]) Clear() {
 	c.Lock()
 	c.items = make(map[K]V)
+	if c.topk != nil {
+		c.topk.reset(c.items)
+	}
 	c.Unlock()
 }
 
@@ -360,6 +377,7 @@ func (c *WriteHeavyCacheInteger[K, V]) Clear() {
 // WARNING: This method does not create a copy of the map.
 // Concurrent modifications to the returned map may cause race conditions
 // and undefined behavior. Use this method with caution in concurrent environments.
+// Modifications made through the returned map are not reflected in TopK.
 func (c *WriteHeavyCacheInteger[K, V]) GetItems() map[K]V {
 	c.Lock()
 	defer c.Unlock()
@@ -374,6 +392,9 @@ func (c *WriteHeavyCacheInteger[K, V]) SetItems(items map[K]V) {
 	c.Lock()
 	defer c.Unlock()
 	c.items = items
+	if c.topk != nil {
+		c.topk.reset(c.items)
+	}
 }
 
 // Size returns the number of items currently in the cache.
@@ -383,10 +404,34 @@ func (c *WriteHeavyCacheInteger[K, V]) Size() int {
 	return len(c.items)
 }
 
+// EnableTopK starts tracking the k keys with the largest values in the cache,
+// so that TopK can answer without sorting all items.
+// Tracking is initialized from the current items and kept in sync by Set, Incr,
+// Delete, Clear and SetItems.
+func (c *WriteHeavyCacheInteger[K, V]) EnableTopK(k int) {
+	c.Lock()
+	defer c.Unlock()
+	c.topk = newTopKTracker(k, c.items)
+}
+
+// TopK returns up to n keys with the largest values, ordered by descending value.
+// n is capped at the k passed to EnableTopK. It returns nil if EnableTopK has not been called.
+func (c *WriteHeavyCacheInteger[K, V]) TopK(n int) []Pair[K, V] {
+	c.Lock()
+	defer c.Unlock()
+	if c.topk == nil {
+		return nil
+	}
+	return c.topk.top(n, c.items)
+}
+
 // Set sets a value in ReadHeavyCacheInteger, locking for the write operation
 func (c *ReadHeavyCacheInteger[K, V]) Set(key K, value V) {
 	c.Lock()
 	c.items[key] = value
+	if c.topk != nil {
+		c.topk.update(key, value)
+	}
 	c.Unlock()
 }
 
@@ -403,9 +448,11 @@ func (c *ReadHeavyCacheInteger[K, V]) Incr(key K, value V) {
 	c.Lock()
 	v, found := c.items[key]
 	if found {
-		c.items[key] = v + value
-	} else {
-		c.items[key] = value
+		value += v
+	}
+	c.items[key] = value
+	if c.topk != nil {
+		c.topk.update(key, value)
 	}
 	c.Unlock()
 }
@@ -415,12 +462,18 @@ func (c *ReadHeavyCacheInteger[K, V]) Delete(key K) {
 	c.Lock() // Write lock is required for deletion.
 	defer c.Unlock()
 	delete(c.items, key)
+	if c.topk != nil {
+		c.topk.remove(key)
+	}
 }
 
 // Clear removes all items from ReadHeavyCacheExpired.
 func (c *ReadHeavyCacheInteger[K, V]) Clear() {
 	c.Lock()
 	c.items = make(map[K]V)
+	if c.topk != nil {
+		c.topk.reset(c.items)
+	}
 	c.Unlock()
 }
 
@@ -428,6 +481,7 @@ func (c *ReadHeavyCacheInteger[K, V]) Clear() {
 // WARNING: This method does not create a copy of the map.
 // Concurrent modifications to the returned map may cause race conditions
 // and undefined behavior. Use this method with caution in concurrent environments.
+// Modifications made through the returned map are not reflected in TopK.
 func (c *ReadHeavyCacheInteger[K, V]) GetItems() map[K]V {
 	c.RLock()
 	defer c.RUnlock()
@@ -442,6 +496,9 @@ func (c *ReadHeavyCacheInteger[K, V]) SetItems(items map[K]V) {
 	c.Lock()
 	defer c.Unlock()
 	c.items = items
+	if c.topk != nil {
+		c.topk.reset(c.items)
+	}
 }
 
 // Size returns the number of items currently in the cache.
@@ -451,12 +508,46 @@ func (c *ReadHeavyCacheInteger[K, V]) Size() int {
 	return len(c.items)
 }
 
+// EnableTopK starts tracking the k keys with the largest values in the cache,
+// so that TopK can answer without sorting all items.
+// Tracking is initialized from the current items and kept in sync by Set, Incr,
+// Delete, Clear and SetItems.
+func (c *ReadHeavyCacheInteger[K, V]) EnableTopK(k int) {
+	c.Lock()
+	defer c.Unlock()
+	c.topk = newTopKTracker(k, c.items)
+}
+
+// TopK returns up to n keys with the largest values, ordered by descending value.
+// n is capped at the k passed to EnableTopK. It returns nil if EnableTopK has not been called.
+func (c *ReadHeavyCacheInteger[K, V]) TopK(n int) []Pair[K, V] {
+	c.RLock()
+	if c.topk == nil || !c.topk.dirty {
+		defer c.RUnlock()
+		if c.topk == nil {
+			return nil
+		}
+		return c.topk.top(n, c.items)
+	}
+	c.RUnlock()
+
+	// The tracker must be rebuilt, which needs the write lock.
+	c.Lock()
+	defer c.Unlock()
+	if c.topk == nil {
+		return nil
+	}
+	return c.topk.top(n, c.items)
+}
+
 // RollingCache is a thread-safe cache that uses a slice for storing elements.
 // It supports Append and Rotate operations, and maintains an initial length for reset.
 type RollingCache[V any] struct {
 	sync.Mutex
-	items  []V // Slice to store values
-	length int // Initial length of the slice for reset
+	items  []V           // Slice to store values
+	length int           // Initial length of the slice for reset
+	free   [][]V         // Slices handed back by Release, reused by Rotate
+	notify chan struct{} // Closed by the next Append, created on demand by Notify and Wait
 }
 
 // NewRollingCache creates a new RollingCache with the specified initial length.
@@ -474,19 +565,103 @@ func (c *RollingCache[V]) Append(value V) {
 
 	// Append the new value to the slice
 	c.items = append(c.items, value)
+
+	// Wake up goroutines waiting in Notify or Wait
+	if c.notify != nil {
+		close(c.notify)
+		c.notify = nil
+	}
 }
 
 // Rotate returns the current slice and replaces it with an empty slice of the initial length.
+// If slices have been handed back with Release, one of them is reused instead of allocating.
 func (c *RollingCache[V]) Rotate() []V {
 	c.Lock()
 	defer c.Unlock()
 
-	// Return the current items and reset the slice
+	return c.rotate()
+}
+
+// rotate returns the current items and resets the slice. The caller must hold the lock.
+func (c *RollingCache[V]) rotate() []V {
 	oldItems := c.items
-	c.items = make([]V, 0, c.length)
+	if n := len(c.free); n > 0 {
+		c.items = c.free[n-1]
+		c.free[n-1] = nil
+		c.free = c.free[:n-1]
+	} else {
+		c.items = make([]V, 0, c.length)
+	}
 	return oldItems
 }
 
+// RotateInto returns the current slice and replaces it with dst, emptied, so the caller
+// can provide the next buffer instead of Rotate allocating one.
+// The caller must not use dst after calling RotateInto.
+func (c *RollingCache[V]) RotateInto(dst []V) []V {
+	clear(dst[:cap(dst)]) // Drop references so values can be garbage collected
+
+	c.Lock()
+	defer c.Unlock()
+
+	oldItems := c.items
+	c.items = dst[:0]
+	return oldItems
+}
+
+// Release hands a slice returned by Rotate back to the cache so a later Rotate can reuse it.
+// The caller must not use items after calling Release.
+func (c *RollingCache[V]) Release(items []V) {
+	if cap(items) == 0 {
+		return
+	}
+	clear(items[:cap(items)]) // Drop references so values can be garbage collected
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.free = append(c.free, items[:0])
+}
+
+// Notify returns a channel that is closed when the next value is appended.
+// Each call after that Append returns a new channel.
+func (c *RollingCache[V]) Notify() <-chan struct{} {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.notifyChan()
+}
+
+// Wait blocks until at least minItems values have been appended, then rotates the
+// cache and returns the values. It returns ctx.Err() if ctx is done first, leaving
+// the values in the cache.
+func (c *RollingCache[V]) Wait(ctx context.Context, minItems int) ([]V, error) {
+	for {
+		c.Lock()
+		if len(c.items) >= minItems {
+			items := c.rotate()
+			c.Unlock()
+			return items, nil
+		}
+		ch := c.notifyChan()
+		c.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// notifyChan returns the channel closed by the next Append. The caller must hold the lock.
+func (c *RollingCache[V]) notifyChan() chan struct{} {
+	if c.notify == nil {
+		c.notify = make(chan struct{})
+	}
+	return c.notify
+}
+
 // GetItems returns a copy of the current slice.
 func (c *RollingCache[V]) GetItems() []V {
 	c.Lock()
@@ -506,61 +681,360 @@ func (c *RollingCache[V]) Size() int {
 	return len(c.items)
 }
 
-// LockManager manages a set of mutexes identified by keys of type K.
-// It is designed to provide fine-grained locking for operations on individual keys.
-type LockManager[K comparable] struct {
+// lockManagerShards is the number of shards in LockManager. It must be a power of two.
+const lockManagerShards = 64
+
+// lockShardFree is the maximum number of unused keyLocks kept per shard for reuse.
+const lockShardFree = 8
+
+// Unlocker releases a lock acquired by GetAndLock.
+type Unlocker interface {
+	Unlock()
+}
+
+// keyedLocks is a sharded map of reference-counted per-key locks of type L,
+// shared by LockManager and RWLockManager. A key's lock is created when the first
+// goroutine acquires it and removed once no goroutine holds or waits for it.
+type keyedLocks[K comparable, L any] struct {
+	seed    maphash.Seed
+	newLock func(*L) // Initializes a new lock, or nil if the zero value is ready to use
+	shards  [lockManagerShards]lockShard[K, L]
+}
+
+// lockShard is a part of keyedLocks' map, guarded by its own Mutex.
+type lockShard[K comparable, L any] struct {
 	mu    sync.Mutex
-	locks map[K]*sync.Mutex
+	locks map[K]*keyLock[K, L]
+	free  []*keyLock[K, L] // Removed locks kept to avoid an allocation per Lock
+	_     [24]byte         // Padding to keep shards on separate cache lines
 }
 
-// NewLockManager creates a new instance of LockManager.
-func NewLockManager[K comparable]() *LockManager[K] {
-	return &LockManager[K]{
-		locks: make(map[K]*sync.Mutex),
+// keyLock is the lock of a single key.
+type keyLock[K comparable, L any] struct {
+	lock  L
+	refs  int // Number of goroutines holding or waiting for lock, guarded by shard.mu
+	id    K
+	shard *lockShard[K, L]
+}
+
+func (m *keyedLocks[K, L]) init(newLock func(*L)) {
+	m.seed = maphash.MakeSeed()
+	m.newLock = newLock
+	for i := range m.shards {
+		m.shards[i].locks = make(map[K]*keyLock[K, L])
 	}
 }
 
-// GetMutex retrieves the mutex associated with the given key, creating it if it doesn't exist.
-// This function uses a double-check locking mechanism to minimize locking overhead
-// for existing mutexes, improving performance in read-heavy scenarios.
-func (lm *LockManager[K]) getMutex(id K) *sync.Mutex {
-	// First check without locking to improve read performance.
-	if lock, exists := lm.locks[id]; exists {
-		return lock
+// shard returns the shard holding the given key.
+func (m *keyedLocks[K, L]) shard(id K) *lockShard[K, L] {
+	return &m.shards[maphash.Comparable(m.seed, id)&(lockManagerShards-1)]
+}
+
+// acquire returns the lock of the given key, creating it if it doesn't exist,
+// and takes a reference to it. The reference must be dropped with release.
+func (m *keyedLocks[K, L]) acquire(id K) *keyLock[K, L] {
+	s := m.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return m.acquireLocked(s, id)
+}
+
+// acquireLocked is like acquire, but the caller must hold s.mu, the shard of the given key.
+func (m *keyedLocks[K, L]) acquireLocked(s *lockShard[K, L], id K) *keyLock[K, L] {
+	l, exists := s.locks[id]
+	if !exists {
+		if n := len(s.free); n > 0 {
+			l = s.free[n-1]
+			s.free = s.free[:n-1]
+			l.id = id
+		} else {
+			l = &keyLock[K, L]{id: id, shard: s}
+			if m.newLock != nil {
+				m.newLock(&l.lock)
+			}
+		}
+		s.locks[id] = l
 	}
+	l.refs++
+	return l
+}
 
-	lm.mu.Lock()
-	defer lm.mu.Unlock()
+// unlock calls unlock on the lock of the given key and drops the reference taken
+// when it was locked. It panics if the key is not locked.
+func (m *keyedLocks[K, L]) unlock(id K, unlock func(*L)) {
+	s := m.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Double-check to avoid race conditions and ensure only one mutex is created for the key.
-	if lock, exists := lm.locks[id]; exists {
-		return lock
+	l, exists := s.locks[id]
+	if !exists {
+		panic("cache: unlock of unlocked key")
+	}
+	unlock(&l.lock)
+	s.release(l)
+}
+
+// size returns the number of keys that are currently locked or waited for.
+func (m *keyedLocks[K, L]) size() int {
+	n := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		n += len(s.locks)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// release drops a reference taken by acquire and removes the lock once it is unused.
+// The caller must hold s.mu.
+func (s *lockShard[K, L]) release(l *keyLock[K, L]) {
+	l.refs--
+	if l.refs == 0 {
+		delete(s.locks, l.id)
+		if len(s.free) < lockShardFree {
+			var zero K
+			l.id = zero // Drop the key so it can be garbage collected
+			s.free = append(s.free, l)
+		}
 	}
+}
+
+// unlockWith calls unlock on the lock and drops the reference taken when it was locked.
+func (l *keyLock[K, L]) unlockWith(unlock func(*L)) {
+	l.shard.mu.Lock()
+	defer l.shard.mu.Unlock()
 
-	lock := &sync.Mutex{}
-	lm.locks[id] = lock
-	return lock
+	unlock(&l.lock)
+	l.shard.release(l)
+}
+
+// drop drops the reference taken by acquire without unlocking, for acquisitions that failed.
+func (l *keyLock[K, L]) drop() {
+	l.shard.mu.Lock()
+	defer l.shard.mu.Unlock()
+
+	l.shard.release(l)
+}
+
+// keyMutex is the mutex of a key in LockManager. It is guarded by the mutex of the
+// key's shard, so locking a free key or unlocking a key without waiters costs a single
+// shard lock. Waiters are handed the lock in FIFO order by closing their channel,
+// which also lets them stop waiting.
+type keyMutex struct {
+	held    bool
+	waiters []chan struct{}
+	lease   *lease // Lease of the current holder, if locked with LockWithLease
+}
+
+// unlock hands the mutex to the first waiter, or releases it if there is none,
+// ending the lease of the current holder. It panics if the mutex is not held.
+func (m *keyMutex) unlock() {
+	if !m.held {
+		panic("cache: unlock of unlocked key")
+	}
+	if m.lease != nil {
+		m.lease.timer.Stop()
+		m.lease = nil
+	}
+	if len(m.waiters) == 0 {
+		m.held = false
+		return
+	}
+	close(m.waiters[0])
+	m.waiters[0] = nil
+	m.waiters = m.waiters[1:]
+}
+
+// LockManager manages a set of mutexes identified by keys of type K.
+// It is designed to provide fine-grained locking for operations on individual keys.
+//
+// The mutex of a key is reference counted: it is created when the first goroutine
+// locks the key and removed once no goroutine holds or waits for it, so locking on
+// an unbounded set of keys such as request IDs does not leak memory.
+// The mutexes are stored in a sharded map, so operations on different keys rarely contend.
+//
+// Besides blocking with Lock, a key can be locked without waiting with TryLock,
+// or while honoring a context deadline or cancellation with LockContext.
+// Locking a free key and unlocking a key nobody waits for take a single shard lock;
+// only goroutines that have to wait block on a channel.
+//
+// A LockManager created with WithLockStats also records per-key contention
+// statistics, reported by Stats, and the keys currently held, reported by Held.
+//
+// LockWithLease locks a key for a limited time, after which it is unlocked
+// automatically unless the lease is renewed.
+type LockManager[K comparable] struct {
+	locks keyedLocks[K, keyMutex]
+	stats *lockStats[K] // nil unless created with WithLockStats
+	fence atomic.Uint64 // Last LeaseToken issued
+}
+
+// lockedKey is the Unlocker returned by LockManager.GetAndLock.
+type lockedKey[K comparable] keyLock[K, keyMutex]
+
+// Unlock unlocks the key and drops the reference taken when it was locked.
+func (l *lockedKey[K]) Unlock() {
+	(*keyLock[K, keyMutex])(l).unlockWith((*keyMutex).unlock)
+}
+
+// statsLockedKey is the Unlocker returned by LockManager.GetAndLock when statistics are enabled.
+type statsLockedKey[K comparable] struct {
+	lm *LockManager[K]
+	id K
+}
+
+// Unlock unlocks the key, recording how long it was held.
+func (l statsLockedKey[K]) Unlock() {
+	l.lm.Unlock(l.id)
+}
+
+// NewLockManager creates a new instance of LockManager.
+func NewLockManager[K comparable](opts ...LockManagerOption) *LockManager[K] {
+	var c lockManagerConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	lm := &LockManager[K]{}
+	lm.locks.init(nil)
+	if c.stats {
+		lm.stats = newLockStats[K]()
+	}
+	return lm
 }
 
 // Lock locks the mutex associated with the given key.
 func (lm *LockManager[K]) Lock(id K) {
-	lm.getMutex(id).Lock()
+	var start time.Time
+	if lm.stats != nil {
+		start = time.Now()
+	}
+	_, ready := lm.lock(id, true)
+	if ready != nil {
+		if lm.stats != nil {
+			lm.stats.waiting(id)
+		}
+		<-ready
+	}
+	if lm.stats != nil {
+		lm.stats.locked(id, start, ready != nil)
+	}
+}
+
+// lock takes a reference to the key and locks it if it is free. If the key is held
+// and wait is set, it queues a waiter and returns a channel that is closed once the
+// lock has been handed over; otherwise it drops the reference and returns a nil keyLock.
+func (lm *LockManager[K]) lock(id K, wait bool) (l *keyLock[K, keyMutex], ready chan struct{}) {
+	s := lm.locks.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l = lm.locks.acquireLocked(s, id)
+	if !l.lock.held {
+		l.lock.held = true
+		return l, nil
+	}
+	if !wait {
+		s.release(l)
+		return nil, nil
+	}
+	ready = make(chan struct{})
+	l.lock.waiters = append(l.lock.waiters, ready)
+	return l, ready
 }
 
-// GetAndLock retrieves the mutex associated with the given key, locks it, and returns the locked mutex.
+// GetAndLock locks the mutex associated with the given key and returns an Unlocker for it.
 // This is useful for cases where you want to obtain and lock the mutex in a single line.
 // For example, you can use it like this:
 //
 //	defer lm.GetAndLock(id).Unlock()
 //
 // This pattern allows you to ensure the mutex is unlocked when the surrounding function exits.
-func (lm *LockManager[K]) GetAndLock(id K) *sync.Mutex {
-	mu := lm.getMutex(id)
-	mu.Lock()
-	return mu
+func (lm *LockManager[K]) GetAndLock(id K) Unlocker {
+	if lm.stats != nil {
+		lm.Lock(id)
+		return statsLockedKey[K]{lm: lm, id: id}
+	}
+	l, ready := lm.lock(id, true)
+	if ready != nil {
+		<-ready
+	}
+	return (*lockedKey[K])(l)
+}
+
+// TryLock tries to lock the mutex associated with the given key without waiting
+// and reports whether it succeeded.
+func (lm *LockManager[K]) TryLock(id K) bool {
+	if l, _ := lm.lock(id, false); l == nil {
+		if lm.stats != nil {
+			lm.stats.failed(id, time.Now())
+		}
+		return false
+	}
+	if lm.stats != nil {
+		lm.stats.locked(id, time.Now(), false)
+	}
+	return true
+}
+
+// LockContext locks the mutex associated with the given key, waiting until it is
+// available or ctx is done. It returns ctx.Err() if the key could not be locked in time.
+func (lm *LockManager[K]) LockContext(ctx context.Context, id K) error {
+	var start time.Time
+	if lm.stats != nil {
+		start = time.Now()
+	}
+	// A free key is taken even if ctx is already done
+	l, ready := lm.lock(id, true)
+	if ready != nil {
+		if lm.stats != nil {
+			lm.stats.waiting(id)
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			lm.abandon(l, ready)
+			if lm.stats != nil {
+				lm.stats.failed(id, start)
+			}
+			return ctx.Err()
+		}
+	}
+	if lm.stats != nil {
+		lm.stats.locked(id, start, ready != nil)
+	}
+	return nil
+}
+
+// abandon stops waiting for l and drops the reference taken by lock.
+func (lm *LockManager[K]) abandon(l *keyLock[K, keyMutex], ready chan struct{}) {
+	s := l.shard
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-ready:
+		// Handed over while giving up; pass the lock on.
+		l.lock.unlock()
+	default:
+		i := slices.Index(l.lock.waiters, ready)
+		l.lock.waiters = slices.Delete(l.lock.waiters, i, i+1)
+	}
+	s.release(l)
 }
 
 // Unlock unlocks the mutex associated with the given key.
+// It panics if the key is not locked.
 func (lm *LockManager[K]) Unlock(id K) {
-	lm.getMutex(id).Unlock()
+	if lm.stats != nil {
+		lm.stats.unlocked(id)
+	}
+	lm.locks.unlock(id, (*keyMutex).unlock)
+}
+
+// Size returns the number of keys that are currently locked or waited for.
+func (lm *LockManager[K]) Size() int {
+	return lm.locks.size()
 }
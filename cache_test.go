@@ -1,6 +1,8 @@
 package cache_test
 
 import (
+	"context"
+	"errors"
 	"runtime"
 	"sync"
 	"testing"
@@ -902,3 +904,304 @@ func TestLockManager(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestRollingCache_Release(t *testing.T) {
+	rollingCache := cache.NewRollingCache[int](10)
+
+	rollingCache.Append(1)
+	rollingCache.Append(2)
+	rotated := rollingCache.Rotate()
+	rollingCache.Release(rotated)
+
+	// The released slice is emptied
+	if rotated[0] != 0 || rotated[1] != 0 {
+		t.Errorf("Expected released slice to be cleared, but got %v", rotated)
+	}
+
+	rollingCache.Append(3)
+	rollingCache.Rotate() // The current slice is replaced with the released one
+	rollingCache.Append(4)
+	if items := rollingCache.Rotate(); &items[0] != &rotated[0] {
+		t.Errorf("Expected Rotate to reuse the released slice")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		rollingCache.Append(1)
+		rollingCache.Release(rollingCache.Rotate())
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations per rotation, but got %v", allocs)
+	}
+}
+
+func TestRollingCache_RotateInto(t *testing.T) {
+	rollingCache := cache.NewRollingCache[int](10)
+	buf := make([]int, 0, 10)
+
+	rollingCache.Append(1)
+	rollingCache.Append(2)
+	buf = rollingCache.RotateInto(buf)
+	if len(buf) != 2 || buf[0] != 1 || buf[1] != 2 {
+		t.Errorf("Expected rotated [1 2], but got %v", buf)
+	}
+
+	rollingCache.Append(3)
+	buf = rollingCache.RotateInto(buf)
+	if len(buf) != 1 || buf[0] != 3 {
+		t.Errorf("Expected rotated [3], but got %v", buf)
+	}
+}
+
+func TestRollingCache_Wait(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		rollingCache := cache.NewRollingCache[int](10)
+		rollingCache.Append(1)
+
+		var items []int
+		var err error
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			items, err = rollingCache.Wait(context.Background(), 3)
+		})
+
+		rollingCache.Append(2)
+		synctest.Wait() // still waiting for a third value
+		if rollingCache.Size() != 2 {
+			t.Errorf("Expected Wait not to rotate before minItems values are appended")
+		}
+
+		rollingCache.Append(3)
+		wg.Wait()
+		if err != nil {
+			t.Errorf("Wait error: %v", err)
+		}
+		if len(items) != 3 || items[0] != 1 || items[2] != 3 {
+			t.Errorf("Expected [1 2 3], but got %v", items)
+		}
+		if rollingCache.Size() != 0 {
+			t.Errorf("Expected cache to be empty after Wait")
+		}
+	})
+}
+
+func TestRollingCache_WaitContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		rollingCache := cache.NewRollingCache[int](10)
+		rollingCache.Append(1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		items, err := rollingCache.Wait(ctx, 2)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, but got %v", err)
+		}
+		if items != nil {
+			t.Errorf("Expected no items, but got %v", items)
+		}
+		if rollingCache.Size() != 1 {
+			t.Errorf("Expected values to stay in the cache after a timeout")
+		}
+	})
+}
+
+func TestRollingCache_Notify(t *testing.T) {
+	rollingCache := cache.NewRollingCache[int](10)
+	ch := rollingCache.Notify()
+
+	select {
+	case <-ch:
+		t.Fatalf("Expected Notify not to fire before Append")
+	default:
+	}
+
+	rollingCache.Append(1)
+	select {
+	case <-ch:
+	default:
+		t.Fatalf("Expected Notify to fire after Append")
+	}
+
+	if next := rollingCache.Notify(); next == ch {
+		t.Errorf("Expected a new channel after Append")
+	}
+}
+
+func TestLockManager_ConcurrentKeys(t *testing.T) {
+	lm := cache.NewLockManager[int]()
+
+	const goroutines = 16
+	const iterations = 1000
+	const existingKeys = 8
+
+	// counters[key] is only modified while holding the key's lock,
+	// so the race detector reports any broken mutual exclusion.
+	counters := make([]int, existingKeys+goroutines*iterations)
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Go(func() {
+			for i := range iterations {
+				// Alternate between shared keys and keys nobody has used yet
+				key := i % existingKeys
+				if i%2 == 1 {
+					key = existingKeys + g*iterations + i
+				}
+				lm.Lock(key)
+				counters[key]++
+				lm.Unlock(key)
+			}
+		})
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range counters {
+		total += n
+	}
+	if total != goroutines*iterations {
+		t.Errorf("Expected %d increments, but got %d", goroutines*iterations, total)
+	}
+}
+
+// Benchmark for LockManager with concurrent lock and unlock of existing keys
+func BenchmarkLockManager_ParallelLock(b *testing.B) {
+	lm := cache.NewLockManager[int]()
+	for i := range 1000 {
+		lm.Lock(i)
+		lm.Unlock(i)
+	}
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			lm.Lock(i % 1000)
+			lm.Unlock(i % 1000)
+			i++
+		}
+	})
+}
+
+func TestLockManager_RemovesUnusedKeys(t *testing.T) {
+	lm := cache.NewLockManager[int]()
+
+	keys := 1_000_000
+	if testing.Short() {
+		keys = 10_000
+	}
+
+	for i := range keys {
+		lm.Lock(i)
+		lm.Unlock(i)
+	}
+	if size := lm.Size(); size != 0 {
+		t.Errorf("Expected size 0 after sequential use, but got %d", size)
+	}
+
+	var wg sync.WaitGroup
+	const goroutines = 8
+	for g := range goroutines {
+		wg.Go(func() {
+			for i := range keys / goroutines {
+				key := i
+				if i%2 == 1 {
+					key = g*keys + i // unique key
+				}
+				lm.GetAndLock(key).Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	if size := lm.Size(); size != 0 {
+		t.Errorf("Expected size 0 after concurrent use, but got %d", size)
+	}
+
+	// A held key is kept until it is unlocked
+	unlock := lm.GetAndLock(1)
+	if size := lm.Size(); size != 1 {
+		t.Errorf("Expected size 1 while a key is held, but got %d", size)
+	}
+	unlock.Unlock()
+	if size := lm.Size(); size != 0 {
+		t.Errorf("Expected size 0 after unlocking, but got %d", size)
+	}
+}
+
+func TestLockManager_UnlockOfUnlockedKey(t *testing.T) {
+	lm := cache.NewLockManager[int]()
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected Unlock of an unlocked key to panic")
+		}
+	}()
+	lm.Unlock(1)
+}
+
+func TestLockManager_TryLock(t *testing.T) {
+	lm := cache.NewLockManager[string]()
+
+	if !lm.TryLock("order1") {
+		t.Fatalf("Expected TryLock to succeed on a free key")
+	}
+	if lm.TryLock("order1") {
+		t.Errorf("Expected TryLock to fail on a locked key")
+	}
+	if !lm.TryLock("order2") {
+		t.Errorf("Expected TryLock to succeed on another key")
+	}
+	lm.Unlock("order2")
+
+	lm.Unlock("order1")
+	if !lm.TryLock("order1") {
+		t.Errorf("Expected TryLock to succeed after Unlock")
+	}
+	lm.Unlock("order1")
+
+	if size := lm.Size(); size != 0 {
+		t.Errorf("Expected failed TryLock not to leave keys behind, but got size %d", size)
+	}
+}
+
+func TestLockManager_LockContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		lm := cache.NewLockManager[int]()
+		lm.Lock(1)
+
+		// Timeout while another goroutine holds the key
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := lm.LockContext(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, but got %v", err)
+		}
+
+		// Cancellation of a waiting goroutine
+		ctx, cancel = context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		var err error
+		wg.Go(func() {
+			err = lm.LockContext(ctx, 1)
+		})
+		synctest.Wait() // the goroutine is waiting for the key
+		cancel()
+		wg.Wait()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, but got %v", err)
+		}
+
+		// A waiter acquires the key once it is released
+		wg.Go(func() {
+			if err := lm.LockContext(context.Background(), 1); err != nil {
+				t.Errorf("LockContext error: %v", err)
+				return
+			}
+			lm.Unlock(1)
+		})
+		synctest.Wait()
+		lm.Unlock(1)
+		wg.Wait()
+
+		if size := lm.Size(); size != 0 {
+			t.Errorf("Expected abandoned waits not to leave keys behind, but got size %d", size)
+		}
+	})
+}
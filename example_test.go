@@ -330,3 +330,101 @@ func ExampleSingleflightGroup() {
 	fmt.Println("Value:", v)
 	// Output: Value: result
 }
+
+func ExampleSingleflightGroup_DoChan() {
+	sf := cache.NewSingleflightGroup[string]()
+
+	select {
+	case res := <-sf.DoChan("example_key", func() (string, error) {
+		return "result", nil
+	}):
+		fmt.Println("Value:", res.Val, "Shared:", res.Shared)
+	case <-time.After(time.Second):
+		fmt.Println("Timeout")
+	}
+	// Output: Value: result Shared: false
+}
+
+// Example for RateLimiter
+func ExampleRateLimiter() {
+	l := cache.NewRateLimiter[string](1, 2) // 1 event per second, bursts of 2
+
+	fmt.Println(l.Allow("user1"))
+	fmt.Println(l.Allow("user1"))
+	fmt.Println(l.Allow("user1"))
+	fmt.Println(l.Allow("user2"))
+	// Output:
+	// true
+	// true
+	// false
+	// true
+}
+
+// Example for ReadHeavyCacheInteger TopK
+func ExampleReadHeavyCacheInteger_TopK() {
+	c := cache.NewReadHeavyCacheInteger[string, int]()
+	c.EnableTopK(2)
+
+	c.Incr("apple", 3)
+	c.Incr("banana", 5)
+	c.Incr("cherry", 1)
+	c.Incr("apple", 4)
+
+	for _, p := range c.TopK(2) {
+		fmt.Println(p.Key, p.Value)
+	}
+	// Output:
+	// apple 7
+	// banana 5
+}
+
+// Example for BoundedRollingCache Latest
+func ExampleBoundedRollingCache_Latest() {
+	c := cache.NewBoundedRollingCache[int](3, cache.OverflowDropOldest)
+
+	for i := 1; i <= 5; i++ {
+		c.Append(i)
+	}
+
+	fmt.Println("Latest 2:", c.Latest(2))
+	fmt.Println("Rotated items:", c.Rotate())
+	// Output:
+	// Latest 2: [4 5]
+	// Rotated items: [3 4 5]
+}
+
+// Example for RollingSet Rotate
+func ExampleRollingSet_Rotate() {
+	s := cache.NewRollingSet[int](10)
+
+	// Mark IDs as dirty; duplicates are ignored until the next rotation
+	s.Append(42)
+	s.Append(7)
+	s.Append(42)
+
+	fmt.Println("Dirty IDs:", s.Rotate())
+	fmt.Println("Size after rotation:", s.Size())
+	// Output:
+	// Dirty IDs: [42 7]
+	// Size after rotation: 0
+}
+
+// Example for RWLockManager with GetAndRLock
+func ExampleRWLockManager_GetAndRLock() {
+	lm := cache.NewRWLockManager[int]()
+
+	read := func(id int) {
+		defer lm.GetAndRLock(id).Unlock()
+		fmt.Printf("Reading resource %d\n", id)
+	}
+	write := func(id int) {
+		defer lm.GetAndLock(id).Unlock()
+		fmt.Printf("Writing resource %d\n", id)
+	}
+
+	read(1)
+	write(1)
+	// Output:
+	// Reading resource 1
+	// Writing resource 1
+}
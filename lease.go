@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrLeaseExpired is returned by LockManager.RenewLease and LockManager.UnlockLease
+	// when the token does not belong to the current lease of the key, because the lease
+	// expired or was already released.
+	ErrLeaseExpired = errors.New("cache: lease expired or not held")
+
+	// ErrInvalidLeaseTTL is returned by LockManager.LockWithLease and LockManager.RenewLease
+	// for a non-positive ttl.
+	ErrInvalidLeaseTTL = errors.New("cache: lease ttl must be positive")
+)
+
+// LeaseToken identifies a lease granted by LockManager.LockWithLease.
+// Tokens of a LockManager strictly increase, so they can be used as fencing tokens:
+// a storage system that remembers the largest token it has seen for a key can reject
+// writes from a holder whose lease has expired and been granted to someone else.
+type LeaseToken uint64
+
+// lease is the lease of the current acquisition of a key, stored in its keyMutex
+// and guarded by the shard mutex.
+type lease struct {
+	token    LeaseToken
+	deadline time.Time
+	timer    *time.Timer
+}
+
+// LockWithLease locks the key like Lock and returns a token for a lease of ttl.
+// If the lease is neither renewed with RenewLease nor released with UnlockLease
+// before it expires, the key is unlocked automatically, so a holder that hangs or
+// loses its deferred unlock cannot block the key forever.
+// The lease belongs to this acquisition only: unlocking the key with Unlock also ends it.
+func (lm *LockManager[K]) LockWithLease(id K, ttl time.Duration) (LeaseToken, error) {
+	if ttl <= 0 {
+		return 0, ErrInvalidLeaseTTL
+	}
+	lm.Lock(id)
+
+	s := lm.locks.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := &lease{
+		token:    LeaseToken(lm.fence.Add(1)),
+		deadline: time.Now().Add(ttl),
+	}
+	// The timer cannot observe the lease before it is stored, since expireLease needs s.mu.
+	l.timer = time.AfterFunc(ttl, func() {
+		lm.expireLease(id, l)
+	})
+	s.locks[id].lock.lease = l
+	return l.token, nil
+}
+
+// RenewLease extends the lease of the key to expire ttl from now.
+// It returns ErrLeaseExpired if token is not the current lease of the key.
+func (lm *LockManager[K]) RenewLease(id K, token LeaseToken, ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrInvalidLeaseTTL
+	}
+
+	s := lm.locks.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kl, exists := s.locks[id]
+	if !exists || kl.lock.lease == nil || kl.lock.lease.token != token {
+		return ErrLeaseExpired
+	}
+	kl.lock.lease.deadline = time.Now().Add(ttl)
+	kl.lock.lease.timer.Reset(ttl)
+	return nil
+}
+
+// UnlockLease releases the lease of the key and unlocks it.
+// It returns ErrLeaseExpired, leaving the key untouched, if token is not the current
+// lease of the key, for example when a late holder unlocks after its lease expired.
+func (lm *LockManager[K]) UnlockLease(id K, token LeaseToken) error {
+	if !lm.unlockLease(id, func(l *lease) bool { return l.token == token }) {
+		return ErrLeaseExpired
+	}
+	return nil
+}
+
+// expireLease unlocks the key if l is still its lease and has not been renewed.
+func (lm *LockManager[K]) expireLease(id K, l *lease) {
+	// A renewal may have raced with the timer firing; the reset timer fires again later.
+	lm.unlockLease(id, func(cur *lease) bool { return cur == l && !time.Now().Before(l.deadline) })
+}
+
+// unlockLease unlocks the key if it is held with a lease accepted by match.
+// The check and the unlock happen under the shard mutex, so a key unlocked and
+// locked again in between is never unlocked on behalf of a stale lease.
+func (lm *LockManager[K]) unlockLease(id K, match func(*lease) bool) bool {
+	s := lm.locks.shard(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kl, exists := s.locks[id]
+	if !exists || kl.lock.lease == nil || !match(kl.lock.lease) {
+		return false
+	}
+	if lm.stats != nil {
+		lm.stats.unlocked(id)
+	}
+	kl.lock.unlock()
+	s.release(kl)
+	return true
+}
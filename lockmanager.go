@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"cmp"
+	"context"
+	"hash/maphash"
+	"math/bits"
+	"slices"
+	"sync"
+)
+
+// LockManyFunc locks all the given keys and returns a function that unlocks them.
+// Keys are deduplicated and locked in the order defined by compare, so goroutines
+// locking overlapping sets of keys cannot deadlock as long as they use the same order.
+func (lm *LockManager[K]) LockManyFunc(compare func(a, b K) int, keys ...K) (unlock func()) {
+	keys = canonicalKeys(compare, keys)
+	for _, id := range keys {
+		lm.Lock(id)
+	}
+	return func() {
+		lm.unlockMany(keys)
+	}
+}
+
+// LockManyContextFunc is like LockManyFunc but gives up when ctx is done.
+// In that case the keys locked so far are released and ctx.Err() is returned.
+func (lm *LockManager[K]) LockManyContextFunc(ctx context.Context, compare func(a, b K) int, keys ...K) (unlock func(), err error) {
+	keys = canonicalKeys(compare, keys)
+	for i, id := range keys {
+		if err := lm.LockContext(ctx, id); err != nil {
+			lm.unlockMany(keys[:i])
+			return nil, err
+		}
+	}
+	return func() {
+		lm.unlockMany(keys)
+	}, nil
+}
+
+// LockMany locks all the given keys of lm in ascending order and returns a function that unlocks them.
+// See LockManager.LockManyFunc.
+func LockMany[K cmp.Ordered](lm *LockManager[K], keys ...K) (unlock func()) {
+	return lm.LockManyFunc(cmp.Compare[K], keys...)
+}
+
+// LockManyContext locks all the given keys of lm in ascending order, giving up when ctx is done.
+// See LockManager.LockManyContextFunc.
+func LockManyContext[K cmp.Ordered](ctx context.Context, lm *LockManager[K], keys ...K) (unlock func(), err error) {
+	return lm.LockManyContextFunc(ctx, cmp.Compare[K], keys...)
+}
+
+// unlockMany unlocks keys in reverse order.
+func (lm *LockManager[K]) unlockMany(keys []K) {
+	for i := len(keys) - 1; i >= 0; i-- {
+		lm.Unlock(keys[i])
+	}
+}
+
+// canonicalKeys returns a sorted copy of keys without duplicates.
+func canonicalKeys[K any](compare func(a, b K) int, keys []K) []K {
+	keys = slices.Clone(keys)
+	slices.SortFunc(keys, compare)
+	return slices.CompactFunc(keys, func(a, b K) bool {
+		return compare(a, b) == 0
+	})
+}
+
+// RWLockManager manages a set of read/write mutexes identified by keys of type K.
+// Readers of the same key do not block each other, while a writer gets exclusive access.
+// Like LockManager, a key's mutex is removed once no goroutine holds or waits for it.
+type RWLockManager[K comparable] struct {
+	locks keyedLocks[K, sync.RWMutex]
+}
+
+// writeLockedKey is the Unlocker returned by RWLockManager.GetAndLock.
+type writeLockedKey[K comparable] keyLock[K, sync.RWMutex]
+
+// Unlock unlocks the key for writing and drops the reference taken when it was locked.
+func (l *writeLockedKey[K]) Unlock() {
+	(*keyLock[K, sync.RWMutex])(l).unlockWith((*sync.RWMutex).Unlock)
+}
+
+// readLockedKey is the Unlocker returned by RWLockManager.GetAndRLock.
+type readLockedKey[K comparable] keyLock[K, sync.RWMutex]
+
+// Unlock unlocks the key for reading and drops the reference taken when it was locked.
+func (l *readLockedKey[K]) Unlock() {
+	(*keyLock[K, sync.RWMutex])(l).unlockWith((*sync.RWMutex).RUnlock)
+}
+
+// NewRWLockManager creates a new instance of RWLockManager.
+func NewRWLockManager[K comparable]() *RWLockManager[K] {
+	lm := &RWLockManager[K]{}
+	lm.locks.init(nil)
+	return lm
+}
+
+// Lock locks the key for writing.
+func (lm *RWLockManager[K]) Lock(id K) {
+	lm.locks.acquire(id).lock.Lock()
+}
+
+// Unlock unlocks the key for writing. It panics if the key is not locked.
+func (lm *RWLockManager[K]) Unlock(id K) {
+	lm.locks.unlock(id, (*sync.RWMutex).Unlock)
+}
+
+// RLock locks the key for reading.
+func (lm *RWLockManager[K]) RLock(id K) {
+	lm.locks.acquire(id).lock.RLock()
+}
+
+// RUnlock unlocks the key for reading. It panics if the key is not locked.
+func (lm *RWLockManager[K]) RUnlock(id K) {
+	lm.locks.unlock(id, (*sync.RWMutex).RUnlock)
+}
+
+// GetAndLock locks the key for writing and returns an Unlocker for it.
+//
+//	defer lm.GetAndLock(id).Unlock()
+func (lm *RWLockManager[K]) GetAndLock(id K) Unlocker {
+	l := lm.locks.acquire(id)
+	l.lock.Lock()
+	return (*writeLockedKey[K])(l)
+}
+
+// GetAndRLock locks the key for reading and returns an Unlocker that releases the read lock.
+//
+//	defer lm.GetAndRLock(id).Unlock()
+func (lm *RWLockManager[K]) GetAndRLock(id K) Unlocker {
+	l := lm.locks.acquire(id)
+	l.lock.RLock()
+	return (*readLockedKey[K])(l)
+}
+
+// Size returns the number of keys that are currently locked or waited for.
+func (lm *RWLockManager[K]) Size() int {
+	return lm.locks.size()
+}
+
+// StripedLockManager provides keyed locking with constant memory.
+// It has the same Lock, Unlock and GetAndLock API as LockManager, but instead of a
+// mutex per key it uses a fixed array of mutexes selected by hashing the key.
+// Different keys may therefore share a mutex and occasionally block each other.
+//
+// Because two keys may map to the same stripe, a goroutine must not hold the lock
+// of one key while locking another, or it may deadlock with itself.
+type StripedLockManager[K comparable] struct {
+	seed    maphash.Seed
+	mask    uint64
+	stripes []lockStripe
+}
+
+// lockStripe is a single mutex of StripedLockManager.
+type lockStripe struct {
+	sync.Mutex
+	_ [56]byte // Padding to keep stripes on separate cache lines
+}
+
+// NewStripedLockManager creates a new StripedLockManager with the given number of
+// stripes, rounded up to a power of two.
+func NewStripedLockManager[K comparable](stripes int) *StripedLockManager[K] {
+	n := 1 << bits.Len(uint(max(stripes, 1)-1))
+	return &StripedLockManager[K]{
+		seed:    maphash.MakeSeed(),
+		mask:    uint64(n - 1),
+		stripes: make([]lockStripe, n),
+	}
+}
+
+// stripe returns the stripe of the given key.
+func (lm *StripedLockManager[K]) stripe(id K) *lockStripe {
+	return &lm.stripes[maphash.Comparable(lm.seed, id)&lm.mask]
+}
+
+// Lock locks the mutex associated with the given key.
+func (lm *StripedLockManager[K]) Lock(id K) {
+	lm.stripe(id).Lock()
+}
+
+// GetAndLock locks the mutex associated with the given key and returns an Unlocker for it.
+//
+//	defer lm.GetAndLock(id).Unlock()
+func (lm *StripedLockManager[K]) GetAndLock(id K) Unlocker {
+	s := lm.stripe(id)
+	s.Lock()
+	return &s.Mutex
+}
+
+// Unlock unlocks the mutex associated with the given key.
+func (lm *StripedLockManager[K]) Unlock(id K) {
+	lm.stripe(id).Unlock()
+}
+
+// Stripes returns the number of mutexes.
+func (lm *StripedLockManager[K]) Stripes() int {
+	return len(lm.stripes)
+}
@@ -0,0 +1,463 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/catatsuy/cache"
+)
+
+func TestRWLockManager_ConcurrentReaders(t *testing.T) {
+	lm := cache.NewRWLockManager[int]()
+
+	// Both readers must hold the read lock at the same time to pass the barrier
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Go(func() {
+			defer lm.GetAndRLock(1).Unlock()
+			barrier.Done()
+			barrier.Wait()
+		})
+	}
+	wg.Wait()
+
+	if size := lm.Size(); size != 0 {
+		t.Errorf("Expected size 0 after unlocking, but got %d", size)
+	}
+}
+
+func TestRWLockManager_ExclusiveWriter(t *testing.T) {
+	lm := cache.NewRWLockManager[int]()
+
+	var readers, writers atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			for range 100 {
+				if i%4 == 0 {
+					lm.Lock(1)
+					if writers.Add(1) != 1 || readers.Load() != 0 {
+						t.Errorf("Expected the writer to have exclusive access")
+					}
+					writers.Add(-1)
+					lm.Unlock(1)
+				} else {
+					lm.RLock(1)
+					readers.Add(1)
+					if writers.Load() != 0 {
+						t.Errorf("Expected no writer while reading")
+					}
+					readers.Add(-1)
+					lm.RUnlock(1)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	unlock := lm.GetAndLock(2)
+	if size := lm.Size(); size != 1 {
+		t.Errorf("Expected size 1 while a key is held, but got %d", size)
+	}
+	unlock.Unlock()
+	if size := lm.Size(); size != 0 {
+		t.Errorf("Expected size 0 after unlocking, but got %d", size)
+	}
+}
+
+func TestLockMany(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		lm := cache.NewLockManager[int]()
+		balances := map[int]int{1: 100, 2: 100}
+
+		// Transfers in opposite directions lock the same keys in caller order 1,2 and 2,1
+		transfer := func(from, to int) {
+			for range 100 {
+				unlock := cache.LockMany(lm, from, to, from)
+				balances[from]--
+				balances[to]++
+				unlock()
+			}
+		}
+		var wg sync.WaitGroup
+		wg.Go(func() { transfer(1, 2) })
+		wg.Go(func() { transfer(2, 1) })
+		wg.Wait()
+
+		if balances[1] != 100 || balances[2] != 100 {
+			t.Errorf("Expected balances to be unchanged, but got %v", balances)
+		}
+		if size := lm.Size(); size != 0 {
+			t.Errorf("Expected size 0 after unlocking, but got %d", size)
+		}
+	})
+}
+
+func TestLockManyContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		lm := cache.NewLockManager[string]()
+		lm.Lock("b")
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		unlock, err := cache.LockManyContext(ctx, lm, "c", "b", "a")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, but got %v", err)
+		}
+		if unlock != nil {
+			t.Errorf("Expected no unlock function on error")
+		}
+		// "a" was locked before waiting on "b" and must have been released
+		if !lm.TryLock("a") {
+			t.Errorf("Expected already acquired keys to be released on cancellation")
+		}
+		lm.Unlock("a")
+		lm.Unlock("b")
+
+		unlock, err = cache.LockManyContext(context.Background(), lm, "c", "b", "a")
+		if err != nil {
+			t.Fatalf("LockManyContext error: %v", err)
+		}
+		if lm.TryLock("b") {
+			t.Errorf("Expected all keys to be locked")
+		}
+		unlock()
+		if size := lm.Size(); size != 0 {
+			t.Errorf("Expected size 0 after unlocking, but got %d", size)
+		}
+	})
+}
+
+func TestLockManager_LockManyFunc(t *testing.T) {
+	type account struct {
+		bank string
+		id   int
+	}
+	compare := func(a, b account) int {
+		if c := strings.Compare(a.bank, b.bank); c != 0 {
+			return c
+		}
+		return a.id - b.id
+	}
+
+	lm := cache.NewLockManager[account]()
+	unlock := lm.LockManyFunc(compare, account{"b", 1}, account{"a", 2}, account{"b", 1})
+	if size := lm.Size(); size != 2 {
+		t.Errorf("Expected 2 deduplicated keys to be locked, but got %d", size)
+	}
+	unlock()
+	if size := lm.Size(); size != 0 {
+		t.Errorf("Expected size 0 after unlocking, but got %d", size)
+	}
+}
+
+func TestStripedLockManager(t *testing.T) {
+	lm := cache.NewStripedLockManager[int](100)
+	if n := lm.Stripes(); n != 128 {
+		t.Errorf("Expected stripes to be rounded up to 128, but got %d", n)
+	}
+
+	counters := make([]int, 64)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for i := range 6400 {
+				key := i % len(counters)
+				if i%2 == 0 {
+					lm.Lock(key)
+					counters[key]++
+					lm.Unlock(key)
+				} else {
+					unlock := lm.GetAndLock(key)
+					counters[key]++
+					unlock.Unlock()
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	for key, n := range counters {
+		if expected := 8 * 6400 / len(counters); n != expected {
+			t.Errorf("Expected %d increments for key %d, but got %d", expected, key, n)
+		}
+	}
+
+	if n := cache.NewStripedLockManager[int](0).Stripes(); n != 1 {
+		t.Errorf("Expected at least 1 stripe, but got %d", n)
+	}
+}
+
+func TestLockManager_Stats(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		lm := cache.NewLockManager[string](cache.WithLockStats())
+
+		lm.Lock("hot")
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer lm.GetAndLock("hot").Unlock()
+			time.Sleep(time.Second)
+		}()
+		time.Sleep(2 * time.Second)
+		if lm.TryLock("hot") {
+			t.Fatalf("Expected TryLock of a held key to fail")
+		}
+		lm.Unlock("hot")
+		synctest.Wait()
+
+		if !lm.TryLock("cold") {
+			t.Fatalf("Expected TryLock of a free key to succeed")
+		}
+		lm.Unlock("cold")
+		<-done
+
+		stats := lm.Stats(10)
+		if len(stats) != 1 {
+			t.Fatalf("Expected stats for the contended key only, got %+v", stats)
+		}
+		want := cache.LockStats[string]{
+			Key:          "hot",
+			Acquisitions: 1,
+			Contended:    1,
+			Failures:     1,
+			WaitTime:     2 * time.Second,
+			MaxWaitTime:  2 * time.Second,
+			HoldTime:     3 * time.Second,
+			MaxHoldTime:  2 * time.Second,
+		}
+		if stats[0] != want {
+			t.Errorf("Expected %+v, got %+v", want, stats[0])
+		}
+		if top := lm.Stats(0); len(top) != 0 {
+			t.Errorf("Expected no keys, got %+v", top)
+		}
+
+		lm.ResetStats()
+		if stats := lm.Stats(10); len(stats) != 0 {
+			t.Errorf("Expected no stats after reset, got %+v", stats)
+		}
+	})
+}
+
+func TestLockManager_StatsBounded(t *testing.T) {
+	lm := cache.NewLockManager[int](cache.WithLockStats())
+
+	for id := range 10000 {
+		lm.Lock(id)
+		lm.Unlock(id)
+	}
+	if stats := lm.Stats(10); len(stats) != 0 {
+		t.Errorf("Expected uncontended keys not to be tracked, got %d keys", len(stats))
+	}
+
+	for id := range 10000 {
+		lm.Lock(id)
+		if lm.TryLock(id) {
+			t.Fatalf("Expected TryLock of a held key to fail")
+		}
+		lm.Unlock(id)
+	}
+	if stats := lm.Stats(10000); len(stats) != 1024 {
+		t.Errorf("Expected stats for at most 1024 keys, got %d", len(stats))
+	}
+}
+
+func TestLockManager_StatsLockContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		lm := cache.NewLockManager[int](cache.WithLockStats())
+
+		lm.Lock(1)
+		ctx, cancel := context.WithTimeout(t.Context(), 500*time.Millisecond)
+		defer cancel()
+		if err := lm.LockContext(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected DeadlineExceeded, got %v", err)
+		}
+		lm.Unlock(1)
+
+		stats := lm.Stats(1)
+		if len(stats) != 1 || stats[0].Failures != 1 || stats[0].WaitTime != 500*time.Millisecond {
+			t.Errorf("Expected one failure waiting 500ms, got %+v", stats)
+		}
+	})
+}
+
+func TestLockManager_Held(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		lm := cache.NewLockManager[string](cache.WithLockStats())
+
+		lm.Lock("a")
+		time.Sleep(time.Second)
+		unlock := cache.LockMany(lm, "b", "c")
+		time.Sleep(time.Second)
+
+		held := lm.Held()
+		if len(held) != 3 {
+			t.Fatalf("Expected 3 held keys, got %+v", held)
+		}
+		if held[0].Key != "a" || held[0].Held != 2*time.Second {
+			t.Errorf("Expected a to be held longest for 2s, got %+v", held[0])
+		}
+		for _, h := range held {
+			if !strings.Contains(h.Holder, "lockmanager_test.go:") {
+				t.Errorf("Expected holder in lockmanager_test.go, got %q", h.Holder)
+			}
+		}
+
+		unlock()
+		lm.Unlock("a")
+		if held := lm.Held(); len(held) != 0 {
+			t.Errorf("Expected no held keys, got %+v", held)
+		}
+	})
+}
+
+func TestLockManager_StatsDisabled(t *testing.T) {
+	lm := cache.NewLockManager[string]()
+	lm.Lock("a")
+	defer lm.Unlock("a")
+
+	if stats := lm.Stats(10); stats != nil {
+		t.Errorf("Expected nil stats, got %+v", stats)
+	}
+	if held := lm.Held(); held != nil {
+		t.Errorf("Expected nil held keys, got %+v", held)
+	}
+}
+
+func TestLockManager_LockWithLease(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		lm := cache.NewLockManager[string]()
+
+		token, err := lm.LockWithLease("a", time.Second)
+		if err != nil {
+			t.Fatalf("LockWithLease: %v", err)
+		}
+		if lm.TryLock("a") {
+			t.Fatalf("Expected a leased key to be locked")
+		}
+		if err := lm.UnlockLease("a", token+1); !errors.Is(err, cache.ErrLeaseExpired) {
+			t.Errorf("Expected ErrLeaseExpired for a wrong token, got %v", err)
+		}
+		if err := lm.UnlockLease("a", token); err != nil {
+			t.Errorf("UnlockLease: %v", err)
+		}
+		if err := lm.UnlockLease("a", token); !errors.Is(err, cache.ErrLeaseExpired) {
+			t.Errorf("Expected ErrLeaseExpired for a released lease, got %v", err)
+		}
+		if size := lm.Size(); size != 0 {
+			t.Errorf("Expected size 0, got %d", size)
+		}
+
+		if _, err := lm.LockWithLease("a", 0); !errors.Is(err, cache.ErrInvalidLeaseTTL) {
+			t.Errorf("Expected ErrInvalidLeaseTTL, got %v", err)
+		}
+	})
+}
+
+func TestLockManager_LeaseExpires(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		lm := cache.NewLockManager[string]()
+
+		old, err := lm.LockWithLease("a", time.Second)
+		if err != nil {
+			t.Fatalf("LockWithLease: %v", err)
+		}
+
+		start := time.Now()
+		next, err := lm.LockWithLease("a", time.Second) // waits for the first lease to expire
+		if err != nil {
+			t.Fatalf("LockWithLease: %v", err)
+		}
+		if waited := time.Since(start); waited != time.Second {
+			t.Errorf("Expected to wait 1s for the lease to expire, waited %v", waited)
+		}
+		if next <= old {
+			t.Errorf("Expected fencing tokens to increase, got %d after %d", next, old)
+		}
+
+		if err := lm.UnlockLease("a", old); !errors.Is(err, cache.ErrLeaseExpired) {
+			t.Errorf("Expected the expired holder to be rejected, got %v", err)
+		}
+		if err := lm.RenewLease("a", old, time.Second); !errors.Is(err, cache.ErrLeaseExpired) {
+			t.Errorf("Expected the expired holder's renewal to be rejected, got %v", err)
+		}
+		if err := lm.UnlockLease("a", next); err != nil {
+			t.Errorf("UnlockLease: %v", err)
+		}
+	})
+}
+
+func TestLockManager_LeaseEndsOnUnlock(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		lm := cache.NewLockManager[string]()
+
+		token, err := lm.LockWithLease("a", time.Second)
+		if err != nil {
+			t.Fatalf("LockWithLease: %v", err)
+		}
+		lm.Unlock("a")
+		lm.GetAndLock("a") // a new holder without a lease
+
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+		if lm.TryLock("a") {
+			t.Fatalf("Expected the lease of an unlocked acquisition not to unlock the new holder")
+		}
+		if err := lm.UnlockLease("a", token); !errors.Is(err, cache.ErrLeaseExpired) {
+			t.Errorf("Expected ErrLeaseExpired for a lease ended by Unlock, got %v", err)
+		}
+		if err := lm.RenewLease("a", token, time.Second); !errors.Is(err, cache.ErrLeaseExpired) {
+			t.Errorf("Expected ErrLeaseExpired for a lease ended by Unlock, got %v", err)
+		}
+		lm.Unlock("a")
+
+		// Expiry after a plain Unlock leaves the free key alone
+		token, err = lm.LockWithLease("b", time.Second)
+		if err != nil {
+			t.Fatalf("LockWithLease: %v", err)
+		}
+		lm.Unlock("b")
+		time.Sleep(2 * time.Second)
+		synctest.Wait()
+		if err := lm.UnlockLease("b", token); !errors.Is(err, cache.ErrLeaseExpired) {
+			t.Errorf("Expected ErrLeaseExpired, got %v", err)
+		}
+		if size := lm.Size(); size != 0 {
+			t.Errorf("Expected size 0, got %d", size)
+		}
+	})
+}
+
+func TestLockManager_RenewLease(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		lm := cache.NewLockManager[string]()
+
+		token, err := lm.LockWithLease("a", time.Second)
+		if err != nil {
+			t.Fatalf("LockWithLease: %v", err)
+		}
+		time.Sleep(900 * time.Millisecond)
+		if err := lm.RenewLease("a", token, time.Second); err != nil {
+			t.Fatalf("RenewLease: %v", err)
+		}
+		time.Sleep(900 * time.Millisecond)
+		if lm.TryLock("a") {
+			t.Fatalf("Expected the renewed lease to keep the key locked")
+		}
+		time.Sleep(200 * time.Millisecond)
+		synctest.Wait()
+		if !lm.TryLock("a") {
+			t.Fatalf("Expected the key to be unlocked after the renewed lease expired")
+		}
+		lm.Unlock("a")
+	})
+}
@@ -0,0 +1,243 @@
+package cache
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lockStatsMaxKeys is the maximum number of keys whose statistics are kept.
+const lockStatsMaxKeys = 1024
+
+// LockManagerOption configures LockManager.
+type LockManagerOption func(*lockManagerConfig)
+
+type lockManagerConfig struct {
+	stats bool
+}
+
+// WithLockStats enables contention statistics and holder tracking, reported by
+// LockManager.Stats and LockManager.Held.
+// Every lock and unlock then reads the clock and updates a map guarded by a single mutex,
+// and GetAndLock allocates, so it is meant for diagnosing contention rather than for hot paths.
+func WithLockStats() LockManagerOption {
+	return func(c *lockManagerConfig) {
+		c.stats = true
+	}
+}
+
+// LockStats is a snapshot of the contention statistics of a key in LockManager.
+// A key is tracked from the first time a goroutine has to wait for it or fails to lock it;
+// counts and times cover the period since then.
+type LockStats[K comparable] struct {
+	Key          K
+	Acquisitions int64         // successful locks
+	Contended    int64         // successful locks that had to wait for another holder
+	Failures     int64         // failed TryLock and LockContext calls
+	WaitTime     time.Duration // total time spent waiting, including failed LockContext calls
+	MaxWaitTime  time.Duration
+	HoldTime     time.Duration // total time the key was held, counted when it is unlocked
+	MaxHoldTime  time.Duration
+}
+
+// HeldLock describes a key that is currently locked in LockManager.
+type HeldLock[K comparable] struct {
+	Key    K
+	Since  time.Time
+	Held   time.Duration // time elapsed since Since
+	Holder string        // file:line of the call that locked the key
+}
+
+// lockStats records the statistics of a LockManager created with WithLockStats.
+type lockStats[K comparable] struct {
+	mu   sync.Mutex
+	keys map[K]*LockStats[K]
+	held map[K]heldLock
+}
+
+type heldLock struct {
+	since  time.Time
+	holder string
+}
+
+func newLockStats[K comparable]() *lockStats[K] {
+	return &lockStats[K]{
+		keys: make(map[K]*LockStats[K]),
+		held: make(map[K]heldLock),
+	}
+}
+
+// key returns the statistics of id, creating them if needed. The caller must hold s.mu.
+// When lockStatsMaxKeys keys are tracked, the one with the least total wait time is dropped.
+func (s *lockStats[K]) key(id K) *LockStats[K] {
+	st, exists := s.keys[id]
+	if exists {
+		return st
+	}
+	if len(s.keys) >= lockStatsMaxKeys {
+		var coldest *LockStats[K]
+		for _, st := range s.keys {
+			if coldest == nil || st.WaitTime < coldest.WaitTime {
+				coldest = st
+			}
+		}
+		delete(s.keys, coldest.Key)
+	}
+	st = &LockStats[K]{Key: id}
+	s.keys[id] = st
+	return st
+}
+
+// waiting records that a goroutine started waiting for id, so that the statistics
+// of id, including the hold time of the current holder, are tracked from now on.
+func (s *lockStats[K]) waiting(id K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.key(id)
+}
+
+// locked records that id was locked after waiting since start.
+func (s *lockStats[K]) locked(id K, start time.Time, contended bool) {
+	holder := lockCaller()
+	now := time.Now()
+	wait := now.Sub(start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.held[id] = heldLock{since: now, holder: holder}
+	st, exists := s.keys[id]
+	if !exists {
+		if !contended {
+			return
+		}
+		st = s.key(id)
+	}
+	st.Acquisitions++
+	if contended {
+		st.Contended++
+	}
+	st.WaitTime += wait
+	st.MaxWaitTime = max(st.MaxWaitTime, wait)
+}
+
+// failed records that locking id was given up after waiting since start.
+func (s *lockStats[K]) failed(id K, start time.Time) {
+	wait := time.Since(start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.key(id)
+	st.Failures++
+	st.WaitTime += wait
+	st.MaxWaitTime = max(st.MaxWaitTime, wait)
+}
+
+// unlocked records that id is about to be unlocked.
+// It must be called before the key is released, so the next holder's record is not lost.
+func (s *lockStats[K]) unlocked(id K) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, exists := s.held[id]
+	if !exists {
+		return
+	}
+	delete(s.held, id)
+	st, exists := s.keys[id]
+	if !exists {
+		return
+	}
+	hold := now.Sub(h.since)
+	st.HoldTime += hold
+	st.MaxHoldTime = max(st.MaxHoldTime, hold)
+}
+
+// lockPackage is the import path of this package, used to skip its frames in lockCaller.
+var lockPackage = reflect.TypeFor[lockManagerConfig]().PkgPath() + "."
+
+// lockCaller returns the file:line of the first caller outside this package.
+func lockCaller() string {
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, lockPackage) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// Stats returns the statistics of up to n contended keys, ordered by descending total wait time.
+// It returns nil unless lm was created with WithLockStats.
+// Keys that were never waited for are not tracked, and at most 1024 keys are kept:
+// once full, tracking a new key drops the one with the least total wait time.
+func (lm *LockManager[K]) Stats(n int) []LockStats[K] {
+	if lm.stats == nil {
+		return nil
+	}
+
+	lm.stats.mu.Lock()
+	stats := make([]LockStats[K], 0, len(lm.stats.keys))
+	for _, st := range lm.stats.keys {
+		stats = append(stats, *st)
+	}
+	lm.stats.mu.Unlock()
+
+	slices.SortFunc(stats, func(a, b LockStats[K]) int {
+		return cmp.Or(
+			cmp.Compare(b.WaitTime, a.WaitTime),
+			cmp.Compare(b.Contended, a.Contended),
+			cmp.Compare(b.Acquisitions, a.Acquisitions),
+		)
+	})
+	if n < len(stats) {
+		stats = stats[:max(n, 0)]
+	}
+	return stats
+}
+
+// ResetStats clears the statistics returned by Stats. Keys that are currently held are not affected.
+func (lm *LockManager[K]) ResetStats() {
+	if lm.stats == nil {
+		return
+	}
+
+	lm.stats.mu.Lock()
+	defer lm.stats.mu.Unlock()
+
+	clear(lm.stats.keys)
+}
+
+// Held returns the keys that are currently locked, longest held first.
+// It returns nil unless lm was created with WithLockStats.
+func (lm *LockManager[K]) Held() []HeldLock[K] {
+	if lm.stats == nil {
+		return nil
+	}
+
+	now := time.Now()
+	lm.stats.mu.Lock()
+	held := make([]HeldLock[K], 0, len(lm.stats.held))
+	for id, h := range lm.stats.held {
+		held = append(held, HeldLock[K]{Key: id, Since: h.since, Held: now.Sub(h.since), Holder: h.holder})
+	}
+	lm.stats.mu.Unlock()
+
+	slices.SortFunc(held, func(a, b HeldLock[K]) int {
+		return a.Since.Compare(b.Since)
+	})
+	return held
+}
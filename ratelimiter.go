@@ -0,0 +1,336 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiterOption configures RateLimiter, SlidingWindowLogLimiter and
+// SlidingWindowCounterLimiter.
+type RateLimiterOption func(*rateLimiterConfig)
+
+type rateLimiterConfig struct {
+	now func() time.Time
+}
+
+// WithRateLimiterClock replaces time.Now as the time source of a limiter.
+// It is mainly intended for deterministic tests.
+func WithRateLimiterClock(now func() time.Time) RateLimiterOption {
+	return func(c *rateLimiterConfig) {
+		c.now = now
+	}
+}
+
+func newRateLimiterConfig(opts []RateLimiterOption) rateLimiterConfig {
+	cfg := rateLimiterConfig{now: time.Now}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// tokenBucket holds the state of a single key in RateLimiter.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is a keyed token-bucket rate limiter.
+// Each key owns a bucket holding up to burst tokens that is refilled at rate tokens per second.
+//
+// A bucket that has been idle long enough to refill completely is indistinguishable
+// from a new one, so such buckets are evicted periodically. Memory usage is therefore
+// bounded by the number of keys that were active within the last refill period.
+type RateLimiter[K comparable] struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     int
+	fill      time.Duration // time to refill an empty bucket, used as the eviction interval
+	now       func() time.Time
+	lastSweep time.Time
+	buckets   map[K]tokenBucket
+}
+
+// NewRateLimiter creates a new RateLimiter that allows rate events per second
+// with bursts of up to burst events per key.
+// It panics if rate or burst is not positive.
+func NewRateLimiter[K comparable](rate float64, burst int, opts ...RateLimiterOption) *RateLimiter[K] {
+	if !(rate > 0) {
+		panic("cache: non-positive rate for NewRateLimiter")
+	}
+	if burst <= 0 {
+		panic("cache: non-positive burst for NewRateLimiter")
+	}
+	cfg := newRateLimiterConfig(opts)
+	return &RateLimiter[K]{
+		rate:      rate,
+		burst:     burst,
+		fill:      time.Duration(float64(burst) / rate * float64(time.Second)),
+		now:       cfg.now,
+		lastSweep: cfg.now(),
+		buckets:   make(map[K]tokenBucket),
+	}
+}
+
+// Allow reports whether a single event for key may happen now.
+func (l *RateLimiter[K]) Allow(key K) bool {
+	return l.AllowN(key, 1)
+}
+
+// AllowN reports whether n events for key may happen now.
+// Tokens are consumed only when the events are allowed.
+func (l *RateLimiter[K]) AllowN(key K, n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b := l.bucket(key, now)
+	if b.tokens < float64(n) {
+		l.buckets[key] = b
+		return false
+	}
+	b.tokens -= float64(n)
+	l.buckets[key] = b
+	return true
+}
+
+// Reserve consumes a token for key unconditionally and returns how long the caller
+// must wait before the event may happen. A zero duration means the event may happen now.
+func (l *RateLimiter[K]) Reserve(key K) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b := l.bucket(key, now)
+	b.tokens--
+	l.buckets[key] = b
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second))
+}
+
+// Size returns the number of keys currently tracked by the limiter.
+func (l *RateLimiter[K]) Size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// bucket returns the bucket for key refilled up to now.
+func (l *RateLimiter[K]) bucket(key K, now time.Time) tokenBucket {
+	b, found := l.buckets[key]
+	if !found {
+		return tokenBucket{tokens: float64(l.burst), last: now}
+	}
+	l.refill(&b, now)
+	return b
+}
+
+func (l *RateLimiter[K]) refill(b *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(l.burst), b.tokens+elapsed.Seconds()*l.rate)
+		b.last = now
+	}
+}
+
+// sweep evicts full buckets once per refill period.
+func (l *RateLimiter[K]) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.fill {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		l.refill(&b, now)
+		if b.tokens >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// SlidingWindowLogLimiter is a keyed rate limiter that allows at most limit events
+// per key within any window of the given duration.
+// It records the timestamp of every allowed event, so it is exact but uses memory
+// proportional to limit for every active key.
+//
+// Keys without events in the last window are evicted periodically.
+type SlidingWindowLogLimiter[K comparable] struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	now       func() time.Time
+	lastSweep time.Time
+	logs      map[K][]time.Time
+}
+
+// NewSlidingWindowLogLimiter creates a new SlidingWindowLogLimiter.
+func NewSlidingWindowLogLimiter[K comparable](limit int, window time.Duration, opts ...RateLimiterOption) *SlidingWindowLogLimiter[K] {
+	cfg := newRateLimiterConfig(opts)
+	return &SlidingWindowLogLimiter[K]{
+		limit:     limit,
+		window:    window,
+		now:       cfg.now,
+		lastSweep: cfg.now(),
+		logs:      make(map[K][]time.Time),
+	}
+}
+
+// Allow reports whether a single event for key may happen now.
+func (l *SlidingWindowLogLimiter[K]) Allow(key K) bool {
+	return l.AllowN(key, 1)
+}
+
+// AllowN reports whether n events for key may happen now.
+// The events are recorded only when they are allowed.
+func (l *SlidingWindowLogLimiter[K]) AllowN(key K, n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	log := l.trim(l.logs[key], now)
+	if len(log)+n > l.limit {
+		l.store(key, log)
+		return false
+	}
+	for range n {
+		log = append(log, now)
+	}
+	l.logs[key] = log
+	return true
+}
+
+// Size returns the number of keys currently tracked by the limiter.
+func (l *SlidingWindowLogLimiter[K]) Size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.logs)
+}
+
+// trim drops the timestamps that are outside the window ending at now.
+func (l *SlidingWindowLogLimiter[K]) trim(log []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(log) && now.Sub(log[i]) >= l.window {
+		i++
+	}
+	if i == 0 {
+		return log
+	}
+	// Shift the remaining timestamps so the backing array does not grow beyond limit.
+	n := copy(log, log[i:])
+	return log[:n]
+}
+
+func (l *SlidingWindowLogLimiter[K]) store(key K, log []time.Time) {
+	if len(log) == 0 {
+		delete(l.logs, key)
+		return
+	}
+	l.logs[key] = log
+}
+
+// sweep evicts keys without events in the last window, once per window.
+func (l *SlidingWindowLogLimiter[K]) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, log := range l.logs {
+		if len(log) == 0 || now.Sub(log[len(log)-1]) >= l.window {
+			delete(l.logs, key)
+		}
+	}
+}
+
+// windowCounter holds the state of a single key in SlidingWindowCounterLimiter.
+type windowCounter struct {
+	start time.Time // start of the current fixed window
+	prev  int       // events in the previous fixed window
+	curr  int       // events in the current fixed window
+}
+
+// SlidingWindowCounterLimiter is a keyed rate limiter that approximates a sliding
+// window by weighting the count of the previous fixed window by how much of it
+// still overlaps the sliding window. It uses constant memory per key.
+//
+// Keys without events in the last two windows are evicted periodically.
+type SlidingWindowCounterLimiter[K comparable] struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	now       func() time.Time
+	lastSweep time.Time
+	counters  map[K]windowCounter
+}
+
+// NewSlidingWindowCounterLimiter creates a new SlidingWindowCounterLimiter.
+func NewSlidingWindowCounterLimiter[K comparable](limit int, window time.Duration, opts ...RateLimiterOption) *SlidingWindowCounterLimiter[K] {
+	cfg := newRateLimiterConfig(opts)
+	return &SlidingWindowCounterLimiter[K]{
+		limit:     limit,
+		window:    window,
+		now:       cfg.now,
+		lastSweep: cfg.now(),
+		counters:  make(map[K]windowCounter),
+	}
+}
+
+// Allow reports whether a single event for key may happen now.
+func (l *SlidingWindowCounterLimiter[K]) Allow(key K) bool {
+	return l.AllowN(key, 1)
+}
+
+// AllowN reports whether n events for key may happen now.
+// The events are counted only when they are allowed.
+func (l *SlidingWindowCounterLimiter[K]) AllowN(key K, n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	c := l.advance(l.counters[key], now)
+	weight := 1 - float64(now.Sub(c.start))/float64(l.window)
+	if float64(c.prev)*weight+float64(c.curr+n) > float64(l.limit) {
+		l.counters[key] = c
+		return false
+	}
+	c.curr += n
+	l.counters[key] = c
+	return true
+}
+
+// Size returns the number of keys currently tracked by the limiter.
+func (l *SlidingWindowCounterLimiter[K]) Size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.counters)
+}
+
+// advance moves c to the fixed window containing now.
+func (l *SlidingWindowCounterLimiter[K]) advance(c windowCounter, now time.Time) windowCounter {
+	start := now.Truncate(l.window)
+	switch {
+	case start.Equal(c.start):
+	case start.Equal(c.start.Add(l.window)):
+		c.prev, c.curr = c.curr, 0
+	default:
+		c.prev, c.curr = 0, 0
+	}
+	c.start = start
+	return c
+}
+
+// sweep evicts keys whose counters have both dropped to zero, once per window.
+func (l *SlidingWindowCounterLimiter[K]) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, c := range l.counters {
+		if c = l.advance(c, now); c.prev == 0 && c.curr == 0 {
+			delete(l.counters, key)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package cache_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/catatsuy/cache"
+)
+
+// fakeClock is a manually advanced time source for the rate limiters.
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestRateLimiter_Allow(t *testing.T) {
+	clock := newFakeClock()
+	l := cache.NewRateLimiter[string](10, 3, cache.WithRateLimiterClock(clock.Now))
+
+	for i := range 3 {
+		if !l.Allow("key1") {
+			t.Errorf("Expected event %d to be allowed within burst", i)
+		}
+	}
+	if l.Allow("key1") {
+		t.Errorf("Expected event to be rejected after burst is exhausted")
+	}
+	if !l.Allow("key2") {
+		t.Errorf("Expected key2 to have its own bucket")
+	}
+
+	clock.Advance(100 * time.Millisecond) // refills one token
+	if !l.Allow("key1") {
+		t.Errorf("Expected event to be allowed after refill")
+	}
+	if l.Allow("key1") {
+		t.Errorf("Expected only one token to be refilled")
+	}
+}
+
+func TestRateLimiter_AllowN(t *testing.T) {
+	clock := newFakeClock()
+	l := cache.NewRateLimiter[string](1, 5, cache.WithRateLimiterClock(clock.Now))
+
+	if !l.AllowN("key1", 4) {
+		t.Errorf("Expected 4 events to be allowed")
+	}
+	if l.AllowN("key1", 2) {
+		t.Errorf("Expected 2 events to be rejected with 1 token left")
+	}
+	if !l.AllowN("key1", 1) {
+		t.Errorf("Expected a rejected AllowN not to consume tokens")
+	}
+	if l.AllowN("key2", 6) {
+		t.Errorf("Expected events beyond burst to be rejected")
+	}
+}
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	clock := newFakeClock()
+	l := cache.NewRateLimiter[string](2, 1, cache.WithRateLimiterClock(clock.Now))
+
+	if d := l.Reserve("key1"); d != 0 {
+		t.Errorf("Expected no delay for the first reservation, got %v", d)
+	}
+	if d := l.Reserve("key1"); d != 500*time.Millisecond {
+		t.Errorf("Expected delay 500ms, got %v", d)
+	}
+	if d := l.Reserve("key1"); d != time.Second {
+		t.Errorf("Expected delay 1s, got %v", d)
+	}
+	if l.Allow("key1") {
+		t.Errorf("Expected reserved tokens to be unavailable")
+	}
+}
+
+func TestRateLimiter_EvictsIdleKeys(t *testing.T) {
+	clock := newFakeClock()
+	l := cache.NewRateLimiter[int](10, 10, cache.WithRateLimiterClock(clock.Now))
+
+	for i := range 100 {
+		l.Allow(i)
+	}
+	if size := l.Size(); size != 100 {
+		t.Errorf("Expected size 100, got %d", size)
+	}
+
+	clock.Advance(time.Second) // every bucket is full again
+	l.Allow(1000)
+	if size := l.Size(); size != 1 {
+		t.Errorf("Expected idle keys to be evicted, got size %d", size)
+	}
+}
+
+func TestRateLimiter_InvalidArguments(t *testing.T) {
+	for _, tt := range []struct {
+		rate  float64
+		burst int
+	}{
+		{0, 1},
+		{-1, 1},
+		{math.NaN(), 1},
+		{1, 0},
+		{1, -1},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected NewRateLimiter(%v, %d) to panic", tt.rate, tt.burst)
+				}
+			}()
+			cache.NewRateLimiter[string](tt.rate, tt.burst)
+		}()
+	}
+}
+
+func TestSlidingWindowLogLimiter_Allow(t *testing.T) {
+	clock := newFakeClock()
+	l := cache.NewSlidingWindowLogLimiter[string](3, time.Second, cache.WithRateLimiterClock(clock.Now))
+
+	l.Allow("key1")
+	clock.Advance(500 * time.Millisecond)
+	if !l.AllowN("key1", 2) {
+		t.Errorf("Expected 2 more events to be allowed")
+	}
+	if l.Allow("key1") {
+		t.Errorf("Expected event to be rejected at the limit")
+	}
+
+	clock.Advance(500 * time.Millisecond) // the first event leaves the window
+	if !l.Allow("key1") {
+		t.Errorf("Expected event to be allowed after the oldest one expired")
+	}
+	if l.Allow("key1") {
+		t.Errorf("Expected event to be rejected at the limit")
+	}
+
+	clock.Advance(2 * time.Second)
+	l.Allow("key2")
+	if size := l.Size(); size != 1 {
+		t.Errorf("Expected idle keys to be evicted, got size %d", size)
+	}
+}
+
+func TestSlidingWindowCounterLimiter_Allow(t *testing.T) {
+	clock := newFakeClock()
+	l := cache.NewSlidingWindowCounterLimiter[string](10, time.Second, cache.WithRateLimiterClock(clock.Now))
+
+	if !l.AllowN("key1", 10) {
+		t.Errorf("Expected 10 events to be allowed")
+	}
+	if l.Allow("key1") {
+		t.Errorf("Expected event to be rejected at the limit")
+	}
+
+	// A quarter into the next window the previous window still weighs 75%.
+	clock.Advance(1250 * time.Millisecond)
+	if !l.AllowN("key1", 2) {
+		t.Errorf("Expected 2 events to be allowed")
+	}
+	if l.Allow("key1") {
+		t.Errorf("Expected event to be rejected by the weighted estimate")
+	}
+
+	clock.Advance(3 * time.Second)
+	if !l.AllowN("key1", 10) {
+		t.Errorf("Expected the limit to be available again after idling")
+	}
+
+	clock.Advance(3 * time.Second)
+	l.Allow("key2")
+	if size := l.Size(); size != 1 {
+		t.Errorf("Expected idle keys to be evicted, got size %d", size)
+	}
+}
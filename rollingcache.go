@@ -0,0 +1,546 @@
+package cache
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRollingCacheFull is returned by Append when the cache is full and the overflow policy is OverflowError.
+var ErrRollingCacheFull = errors.New("cache: rolling cache is full")
+
+// OverflowPolicy determines what Append does when a bounded cache is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest discards the oldest value to make room for the new one.
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowDropNewest discards the value being appended.
+	OverflowDropNewest
+	// OverflowBlock blocks Append until Rotate makes room.
+	OverflowBlock
+	// OverflowError makes Append return ErrRollingCacheFull.
+	OverflowError
+)
+
+// BoundedRollingCache is a RollingCache with a fixed capacity backed by a ring buffer.
+// When the buffer is full, Append follows the configured OverflowPolicy, so memory
+// stays bounded even if the goroutine calling Rotate stalls.
+type BoundedRollingCache[V any] struct {
+	sync.Mutex
+	notFull *sync.Cond // signaled by Rotate for OverflowBlock
+	items   []V        // ring buffer with len(items) == capacity
+	head    int        // index of the oldest value
+	size    int        // number of values in the buffer
+	dropped int        // number of values discarded by the overflow policy
+	policy  OverflowPolicy
+}
+
+// NewBoundedRollingCache creates a new BoundedRollingCache holding at most capacity values.
+// It panics if capacity is not positive.
+func NewBoundedRollingCache[V any](capacity int, policy OverflowPolicy) *BoundedRollingCache[V] {
+	if capacity <= 0 {
+		panic("cache: non-positive capacity for NewBoundedRollingCache")
+	}
+	c := &BoundedRollingCache[V]{
+		items:  make([]V, capacity),
+		policy: policy,
+	}
+	c.notFull = sync.NewCond(&c.Mutex)
+	return c
+}
+
+// Append adds a value to the cache.
+// If the cache is full, the value is handled according to the overflow policy.
+// It returns ErrRollingCacheFull only when the policy is OverflowError.
+func (c *BoundedRollingCache[V]) Append(value V) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.size == len(c.items) {
+		switch c.policy {
+		case OverflowDropOldest:
+			var zero V
+			c.items[c.head] = zero
+			c.head = (c.head + 1) % len(c.items)
+			c.size--
+			c.dropped++
+		case OverflowDropNewest:
+			c.dropped++
+			return nil
+		case OverflowBlock:
+			for c.size == len(c.items) {
+				c.notFull.Wait()
+			}
+		case OverflowError:
+			return ErrRollingCacheFull
+		}
+	}
+
+	c.items[(c.head+c.size)%len(c.items)] = value
+	c.size++
+	return nil
+}
+
+// Rotate returns the current values in append order and empties the cache.
+func (c *BoundedRollingCache[V]) Rotate() []V {
+	c.Lock()
+	defer c.Unlock()
+
+	oldItems := c.copyLatest(c.size)
+	clear(c.items)
+	c.head = 0
+	c.size = 0
+	c.notFull.Broadcast()
+	return oldItems
+}
+
+// GetItems returns a copy of the current values in append order.
+func (c *BoundedRollingCache[V]) GetItems() []V {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.copyLatest(c.size)
+}
+
+// Latest returns a copy of the n most recently appended values in append order.
+// If fewer than n values are present, all of them are returned.
+func (c *BoundedRollingCache[V]) Latest(n int) []V {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.copyLatest(min(max(n, 0), c.size))
+}
+
+// Size returns the number of values currently in the cache.
+func (c *BoundedRollingCache[V]) Size() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.size
+}
+
+// Cap returns the capacity of the cache.
+func (c *BoundedRollingCache[V]) Cap() int {
+	return len(c.items)
+}
+
+// Dropped returns the number of values discarded by OverflowDropOldest or OverflowDropNewest.
+func (c *BoundedRollingCache[V]) Dropped() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.dropped
+}
+
+// copyLatest copies the n newest values out of the ring buffer.
+func (c *BoundedRollingCache[V]) copyLatest(n int) []V {
+	copiedItems := make([]V, n)
+	start := (c.head + c.size - n) % len(c.items)
+	k := copy(copiedItems, c.items[start:min(start+n, len(c.items))])
+	copy(copiedItems[k:], c.items[:n-k])
+	return copiedItems
+}
+
+// StripedRollingCache is a RollingCache for many concurrent producers.
+// It keeps one independently locked stripe per P (GOMAXPROCS). A producer reuses the
+// stripe last used on its P through a sync.Pool, whose fast path reads a per-P slot without
+// shared writes, so producers running on different CPUs almost always lock different stripes.
+// When the pool is emptied by a garbage collection, stripes are handed out again round-robin.
+//
+// Rotate locks every stripe at once, so it returns a consistent cut: each Append either
+// completes before Rotate and is included, or runs after it and is kept for the next rotation.
+// Values are grouped by stripe, so the result is not in global append order.
+type StripedRollingCache[V any] struct {
+	stripes []rollingStripe[V]
+	local   sync.Pool     // *rollingStripe[V] last used on the current P
+	next    atomic.Uint32 // Next stripe handed out when local is empty
+}
+
+// rollingStripe is a single independently locked buffer of StripedRollingCache.
+type rollingStripe[V any] struct {
+	sync.Mutex
+	items []V
+	_     [64]byte // Padding to keep stripes on separate cache lines
+}
+
+// NewStripedRollingCache creates a new StripedRollingCache with GOMAXPROCS stripes.
+// The initial length is spread across the stripes.
+func NewStripedRollingCache[V any](length int) *StripedRollingCache[V] {
+	n := runtime.GOMAXPROCS(0)
+	c := &StripedRollingCache[V]{
+		stripes: make([]rollingStripe[V], n),
+	}
+	for i := range c.stripes {
+		c.stripes[i].items = make([]V, 0, (length+n-1)/n)
+	}
+	c.local.New = func() any {
+		return &c.stripes[int(c.next.Add(1)-1)%len(c.stripes)]
+	}
+	return c
+}
+
+// Append adds a value to the cache.
+func (c *StripedRollingCache[V]) Append(value V) {
+	s := c.local.Get().(*rollingStripe[V])
+	s.Lock()
+	s.items = append(s.items, value)
+	s.Unlock()
+	c.local.Put(s)
+}
+
+// Rotate returns all values appended since the last rotation and empties the cache.
+// The stripe buffers are kept and reused, so only the returned slice is allocated.
+func (c *StripedRollingCache[V]) Rotate() []V {
+	c.lockAll()
+	defer c.unlockAll()
+
+	oldItems := c.collect()
+	for i := range c.stripes {
+		s := &c.stripes[i]
+		clear(s.items)
+		s.items = s.items[:0]
+	}
+	return oldItems
+}
+
+// GetItems returns a copy of the current values.
+func (c *StripedRollingCache[V]) GetItems() []V {
+	c.lockAll()
+	defer c.unlockAll()
+
+	return c.collect()
+}
+
+// Size returns the number of values currently in the cache.
+func (c *StripedRollingCache[V]) Size() int {
+	c.lockAll()
+	defer c.unlockAll()
+
+	n := 0
+	for i := range c.stripes {
+		n += len(c.stripes[i].items)
+	}
+	return n
+}
+
+// collect copies the values of all stripes into a new slice. The caller must hold all stripe locks.
+func (c *StripedRollingCache[V]) collect() []V {
+	n := 0
+	for i := range c.stripes {
+		n += len(c.stripes[i].items)
+	}
+	items := make([]V, 0, n)
+	for i := range c.stripes {
+		items = append(items, c.stripes[i].items...)
+	}
+	return items
+}
+
+func (c *StripedRollingCache[V]) lockAll() {
+	for i := range c.stripes {
+		c.stripes[i].Lock()
+	}
+}
+
+func (c *StripedRollingCache[V]) unlockAll() {
+	for i := range c.stripes {
+		c.stripes[i].Unlock()
+	}
+}
+
+// KeyedRollingCache is a RollingCache that groups appended values by key,
+// so values can be buffered per key and rotated all at once or one key at a time.
+// The number of values per key can be limited; when a key is full, Append follows
+// the configured OverflowPolicy for that key.
+type KeyedRollingCache[K comparable, V any] struct {
+	sync.Mutex
+	notFull *sync.Cond // signaled by Rotate and RotateKey for OverflowBlock
+	items   map[K][]V
+	length  int // Initial length of the slice for each key
+	limit   int // Maximum number of values per key, or 0 for no limit
+	dropped int // Number of values discarded by the overflow policy
+	policy  OverflowPolicy
+}
+
+// NewKeyedRollingCache creates a new KeyedRollingCache.
+// length is the initial length of the slice allocated for each key, and limit is the
+// maximum number of values kept per key, or 0 to let each key grow dynamically.
+func NewKeyedRollingCache[K comparable, V any](length, limit int, policy OverflowPolicy) *KeyedRollingCache[K, V] {
+	c := &KeyedRollingCache[K, V]{
+		items:  make(map[K][]V),
+		length: length,
+		limit:  limit,
+		policy: policy,
+	}
+	c.notFull = sync.NewCond(&c.Mutex)
+	return c
+}
+
+// Append adds a value for key.
+// If key already holds limit values, the value is handled according to the overflow policy.
+// It returns ErrRollingCacheFull only when the policy is OverflowError.
+func (c *KeyedRollingCache[K, V]) Append(key K, value V) error {
+	c.Lock()
+	defer c.Unlock()
+
+	items := c.items[key]
+	if c.limit > 0 && len(items) >= c.limit {
+		switch c.policy {
+		case OverflowDropOldest:
+			n := copy(items, items[1:])
+			var zero V
+			items[n] = zero
+			items = items[:n]
+			c.dropped++
+		case OverflowDropNewest:
+			c.dropped++
+			return nil
+		case OverflowBlock:
+			for len(c.items[key]) >= c.limit {
+				c.notFull.Wait()
+			}
+			items = c.items[key]
+		case OverflowError:
+			return ErrRollingCacheFull
+		}
+	}
+
+	if items == nil {
+		items = make([]V, 0, c.length)
+	}
+	c.items[key] = append(items, value)
+	return nil
+}
+
+// Rotate returns the values of all keys and empties the cache.
+func (c *KeyedRollingCache[K, V]) Rotate() map[K][]V {
+	c.Lock()
+	defer c.Unlock()
+
+	oldItems := c.items
+	c.items = make(map[K][]V)
+	c.notFull.Broadcast()
+	return oldItems
+}
+
+// RotateKey returns the values of key and removes them from the cache.
+// Values of other keys are left untouched.
+func (c *KeyedRollingCache[K, V]) RotateKey(key K) []V {
+	c.Lock()
+	defer c.Unlock()
+
+	oldItems := c.items[key]
+	delete(c.items, key)
+	c.notFull.Broadcast()
+	return oldItems
+}
+
+// GetItems returns a copy of the current values of key.
+func (c *KeyedRollingCache[K, V]) GetItems(key K) []V {
+	c.Lock()
+	defer c.Unlock()
+
+	copiedItems := make([]V, len(c.items[key]))
+	copy(copiedItems, c.items[key])
+	return copiedItems
+}
+
+// Size returns the number of values currently in the cache across all keys.
+func (c *KeyedRollingCache[K, V]) Size() int {
+	c.Lock()
+	defer c.Unlock()
+
+	n := 0
+	for _, items := range c.items {
+		n += len(items)
+	}
+	return n
+}
+
+// Dropped returns the number of values discarded by OverflowDropOldest or OverflowDropNewest.
+func (c *KeyedRollingCache[K, V]) Dropped() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.dropped
+}
+
+// RollingSet is a RollingCache with set semantics between rotations.
+// Appending a value that is already present is a no-op until the next Rotate,
+// and Rotate returns the unique values in the order they were first appended.
+type RollingSet[V comparable] struct {
+	sync.Mutex
+	items  []V            // Unique values in insertion order
+	seen   map[V]struct{} // Values present in items
+	length int            // Initial length of the slice for reset
+}
+
+// NewRollingSet creates a new RollingSet with the specified initial length.
+func NewRollingSet[V comparable](length int) *RollingSet[V] {
+	return &RollingSet[V]{
+		items:  make([]V, 0, length),
+		seen:   make(map[V]struct{}, length),
+		length: length,
+	}
+}
+
+// Append adds a value to the set and reports whether it was added.
+// It returns false if the value has already been appended since the last rotation.
+func (c *RollingSet[V]) Append(value V) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, found := c.seen[value]; found {
+		return false
+	}
+	c.seen[value] = struct{}{}
+	c.items = append(c.items, value)
+	return true
+}
+
+// Contains reports whether the value has been appended since the last rotation.
+func (c *RollingSet[V]) Contains(value V) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	_, found := c.seen[value]
+	return found
+}
+
+// Rotate returns the unique values in insertion order and empties the set.
+func (c *RollingSet[V]) Rotate() []V {
+	c.Lock()
+	defer c.Unlock()
+
+	oldItems := c.items
+	c.items = make([]V, 0, c.length)
+	clear(c.seen) // Keep the map's buckets for the next round
+	return oldItems
+}
+
+// GetItems returns a copy of the current values in insertion order.
+func (c *RollingSet[V]) GetItems() []V {
+	c.Lock()
+	defer c.Unlock()
+
+	copiedItems := make([]V, len(c.items))
+	copy(copiedItems, c.items)
+	return copiedItems
+}
+
+// Size returns the number of unique values currently in the set.
+func (c *RollingSet[V]) Size() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.items)
+}
+
+// Bucket holds the values appended to a BucketedRollingCache during [Start, End).
+type Bucket[V any] struct {
+	Start time.Time
+	End   time.Time
+	Items []V
+}
+
+// BucketedRollingCache is a RollingCache that rotates automatically on wall-clock
+// boundaries that are multiples of interval (for example every minute) and keeps the
+// last retain rotated buckets, so statistics can be computed per interval.
+//
+// Rotation happens lazily when the cache is accessed, so no background goroutine is
+// needed. Intervals without any values still produce empty buckets.
+type BucketedRollingCache[V any] struct {
+	sync.Mutex
+	items    []V       // Values of the current bucket
+	start    time.Time // Start of the current bucket
+	interval time.Duration
+	retain   int         // Number of rotated buckets to keep
+	history  []Bucket[V] // Rotated buckets, oldest first
+	length   int         // Initial length of the slice for each bucket
+}
+
+// NewBucketedRollingCache creates a new BucketedRollingCache that rotates every interval
+// and keeps the last retain buckets. length is the initial length of each bucket's slice.
+func NewBucketedRollingCache[V any](length int, interval time.Duration, retain int) *BucketedRollingCache[V] {
+	return &BucketedRollingCache[V]{
+		items:    make([]V, 0, length),
+		start:    time.Now().Truncate(interval),
+		interval: interval,
+		retain:   retain,
+		history:  make([]Bucket[V], 0, retain),
+		length:   length,
+	}
+}
+
+// Append adds a value to the bucket of the current interval.
+func (c *BucketedRollingCache[V]) Append(value V) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.advance(time.Now())
+	c.items = append(c.items, value)
+}
+
+// Buckets returns copies of the retained rotated buckets, oldest first.
+// The current, still open bucket is not included.
+func (c *BucketedRollingCache[V]) Buckets() []Bucket[V] {
+	c.Lock()
+	defer c.Unlock()
+
+	c.advance(time.Now())
+	buckets := make([]Bucket[V], len(c.history))
+	for i, b := range c.history {
+		copiedItems := make([]V, len(b.Items))
+		copy(copiedItems, b.Items)
+		buckets[i] = Bucket[V]{Start: b.Start, End: b.End, Items: copiedItems}
+	}
+	return buckets
+}
+
+// Current returns a copy of the bucket of the current interval.
+func (c *BucketedRollingCache[V]) Current() Bucket[V] {
+	c.Lock()
+	defer c.Unlock()
+
+	c.advance(time.Now())
+	copiedItems := make([]V, len(c.items))
+	copy(copiedItems, c.items)
+	return Bucket[V]{Start: c.start, End: c.start.Add(c.interval), Items: copiedItems}
+}
+
+// advance rotates the current bucket and any skipped intervals into the history
+// if now is past the end of the current bucket.
+func (c *BucketedRollingCache[V]) advance(now time.Time) {
+	start := now.Truncate(c.interval)
+	if !start.After(c.start) {
+		return
+	}
+
+	c.push(Bucket[V]{Start: c.start, End: c.start.Add(c.interval), Items: c.items})
+	// Only the last retain skipped intervals can still be visible
+	s := c.start.Add(c.interval)
+	if oldest := start.Add(-time.Duration(c.retain) * c.interval); oldest.After(s) {
+		s = oldest
+	}
+	for ; s.Before(start); s = s.Add(c.interval) {
+		c.push(Bucket[V]{Start: s, End: s.Add(c.interval)})
+	}
+
+	c.items = make([]V, 0, c.length)
+	c.start = start
+}
+
+// push appends a rotated bucket to the history, dropping the oldest beyond retain.
+func (c *BucketedRollingCache[V]) push(b Bucket[V]) {
+	if c.retain <= 0 {
+		return
+	}
+	if len(c.history) == c.retain {
+		n := copy(c.history, c.history[1:])
+		c.history = c.history[:n]
+	}
+	c.history = append(c.history, b)
+}
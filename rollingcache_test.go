@@ -0,0 +1,332 @@
+package cache_test
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/catatsuy/cache"
+)
+
+func TestBoundedRollingCache_DropOldest(t *testing.T) {
+	c := cache.NewBoundedRollingCache[int](3, cache.OverflowDropOldest)
+	for i := range 5 {
+		if err := c.Append(i); err != nil {
+			t.Errorf("Append error: %v", err)
+		}
+	}
+
+	if got, expected := c.GetItems(), []int{2, 3, 4}; !slices.Equal(got, expected) {
+		t.Errorf("Expected items %v, but got %v", expected, got)
+	}
+	if got, expected := c.Latest(2), []int{3, 4}; !slices.Equal(got, expected) {
+		t.Errorf("Expected latest %v, but got %v", expected, got)
+	}
+	if dropped := c.Dropped(); dropped != 2 {
+		t.Errorf("Expected 2 dropped values, but got %d", dropped)
+	}
+
+	if got, expected := c.Rotate(), []int{2, 3, 4}; !slices.Equal(got, expected) {
+		t.Errorf("Expected rotated %v, but got %v", expected, got)
+	}
+	if size := c.Size(); size != 0 {
+		t.Errorf("Expected cache to be empty after rotation, but got size %d", size)
+	}
+}
+
+func TestBoundedRollingCache_DropNewest(t *testing.T) {
+	c := cache.NewBoundedRollingCache[int](3, cache.OverflowDropNewest)
+	for i := range 5 {
+		c.Append(i)
+	}
+
+	if got, expected := c.Rotate(), []int{0, 1, 2}; !slices.Equal(got, expected) {
+		t.Errorf("Expected rotated %v, but got %v", expected, got)
+	}
+	if dropped := c.Dropped(); dropped != 2 {
+		t.Errorf("Expected 2 dropped values, but got %d", dropped)
+	}
+}
+
+func TestBoundedRollingCache_Error(t *testing.T) {
+	c := cache.NewBoundedRollingCache[int](2, cache.OverflowError)
+	c.Append(1)
+	c.Append(2)
+	if err := c.Append(3); !errors.Is(err, cache.ErrRollingCacheFull) {
+		t.Errorf("Expected ErrRollingCacheFull, but got %v", err)
+	}
+
+	c.Rotate()
+	if err := c.Append(3); err != nil {
+		t.Errorf("Expected Append to succeed after rotation, but got %v", err)
+	}
+}
+
+func TestBoundedRollingCache_Block(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		c := cache.NewBoundedRollingCache[int](2, cache.OverflowBlock)
+		c.Append(1)
+		c.Append(2)
+
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			c.Append(3)
+		})
+		synctest.Wait() // Append is blocked on the full buffer
+
+		if got, expected := c.Rotate(), []int{1, 2}; !slices.Equal(got, expected) {
+			t.Errorf("Expected rotated %v, but got %v", expected, got)
+		}
+		wg.Wait()
+
+		if got, expected := c.GetItems(), []int{3}; !slices.Equal(got, expected) {
+			t.Errorf("Expected items %v, but got %v", expected, got)
+		}
+	})
+}
+
+func TestBoundedRollingCache_InvalidCapacity(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected NewBoundedRollingCache(%d) to panic", capacity)
+				}
+			}()
+			cache.NewBoundedRollingCache[int](capacity, cache.OverflowDropOldest)
+		}()
+	}
+}
+
+func TestStripedRollingCache_Rotate(t *testing.T) {
+	c := cache.NewStripedRollingCache[int](16)
+	for i := range 5 {
+		c.Append(i)
+	}
+	if size := c.Size(); size != 5 {
+		t.Errorf("Expected size 5, but got %d", size)
+	}
+
+	rotated := c.Rotate()
+	slices.Sort(rotated)
+	if expected := []int{0, 1, 2, 3, 4}; !slices.Equal(rotated, expected) {
+		t.Errorf("Expected rotated %v, but got %v", expected, rotated)
+	}
+	if size := c.Size(); size != 0 {
+		t.Errorf("Expected cache to be empty after rotation, but got size %d", size)
+	}
+}
+
+func TestStripedRollingCache_ConcurrentRotate(t *testing.T) {
+	c := cache.NewStripedRollingCache[int](16)
+
+	const producers = 8
+	const perProducer = 1000
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var rotated []int
+	stop := make(chan struct{})
+	var consumer sync.WaitGroup
+	consumer.Go(func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			items := c.Rotate()
+			mu.Lock()
+			rotated = append(rotated, items...)
+			mu.Unlock()
+		}
+	})
+
+	for p := range producers {
+		wg.Go(func() {
+			for j := range perProducer {
+				c.Append(p*perProducer + j)
+			}
+		})
+	}
+	wg.Wait()
+	close(stop)
+	consumer.Wait()
+	rotated = append(rotated, c.Rotate()...)
+
+	// Every value must be returned exactly once
+	slices.Sort(rotated)
+	if len(rotated) != producers*perProducer {
+		t.Fatalf("Expected %d values, but got %d", producers*perProducer, len(rotated))
+	}
+	for i, v := range rotated {
+		if v != i {
+			t.Fatalf("Expected value %d at %d, but got %d", i, i, v)
+		}
+	}
+}
+
+func TestKeyedRollingCache_Rotate(t *testing.T) {
+	c := cache.NewKeyedRollingCache[string, int](4, 0, cache.OverflowError)
+	c.Append("user1", 1)
+	c.Append("user2", 2)
+	c.Append("user1", 3)
+
+	if size := c.Size(); size != 3 {
+		t.Errorf("Expected size 3, but got %d", size)
+	}
+	if got, expected := c.GetItems("user1"), []int{1, 3}; !slices.Equal(got, expected) {
+		t.Errorf("Expected items %v, but got %v", expected, got)
+	}
+
+	if got, expected := c.RotateKey("user1"), []int{1, 3}; !slices.Equal(got, expected) {
+		t.Errorf("Expected rotated %v, but got %v", expected, got)
+	}
+	if size := c.Size(); size != 1 {
+		t.Errorf("Expected only user2 to remain, but got size %d", size)
+	}
+
+	c.Append("user3", 4)
+	rotated := c.Rotate()
+	if len(rotated) != 2 || !slices.Equal(rotated["user2"], []int{2}) || !slices.Equal(rotated["user3"], []int{4}) {
+		t.Errorf("Expected rotated map[user2:[2] user3:[4]], but got %v", rotated)
+	}
+	if size := c.Size(); size != 0 {
+		t.Errorf("Expected cache to be empty after rotation, but got size %d", size)
+	}
+}
+
+func TestKeyedRollingCache_Limit(t *testing.T) {
+	dropOldest := cache.NewKeyedRollingCache[string, int](4, 2, cache.OverflowDropOldest)
+	dropNewest := cache.NewKeyedRollingCache[string, int](4, 2, cache.OverflowDropNewest)
+	withError := cache.NewKeyedRollingCache[string, int](4, 2, cache.OverflowError)
+	for i := range 3 {
+		dropOldest.Append("key", i)
+		dropNewest.Append("key", i)
+		if err := withError.Append("key", i); i == 2 && !errors.Is(err, cache.ErrRollingCacheFull) {
+			t.Errorf("Expected ErrRollingCacheFull, but got %v", err)
+		}
+	}
+	dropOldest.Append("other", 10) // limits apply per key
+
+	if got, expected := dropOldest.GetItems("key"), []int{1, 2}; !slices.Equal(got, expected) {
+		t.Errorf("Expected items %v with OverflowDropOldest, but got %v", expected, got)
+	}
+	if got, expected := dropOldest.GetItems("other"), []int{10}; !slices.Equal(got, expected) {
+		t.Errorf("Expected items %v for another key, but got %v", expected, got)
+	}
+	if got, expected := dropNewest.GetItems("key"), []int{0, 1}; !slices.Equal(got, expected) {
+		t.Errorf("Expected items %v with OverflowDropNewest, but got %v", expected, got)
+	}
+	if dropped := dropNewest.Dropped(); dropped != 1 {
+		t.Errorf("Expected 1 dropped value, but got %d", dropped)
+	}
+}
+
+func TestKeyedRollingCache_Block(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		c := cache.NewKeyedRollingCache[string, int](4, 1, cache.OverflowBlock)
+		c.Append("key", 1)
+
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			c.Append("key", 2)
+		})
+		synctest.Wait() // Append is blocked on the full key
+
+		if got, expected := c.RotateKey("key"), []int{1}; !slices.Equal(got, expected) {
+			t.Errorf("Expected rotated %v, but got %v", expected, got)
+		}
+		wg.Wait()
+
+		if got, expected := c.GetItems("key"), []int{2}; !slices.Equal(got, expected) {
+			t.Errorf("Expected items %v, but got %v", expected, got)
+		}
+	})
+}
+
+func TestRollingSet_Rotate(t *testing.T) {
+	c := cache.NewRollingSet[int](10)
+
+	for _, v := range []int{3, 1, 3, 2, 1} {
+		c.Append(v)
+	}
+	if size := c.Size(); size != 3 {
+		t.Errorf("Expected size 3, but got %d", size)
+	}
+	if !c.Contains(2) || c.Contains(4) {
+		t.Errorf("Expected set to contain exactly the appended values")
+	}
+
+	if got, expected := c.Rotate(), []int{3, 1, 2}; !slices.Equal(got, expected) {
+		t.Errorf("Expected rotated %v, but got %v", expected, got)
+	}
+
+	// Values can be appended again after rotation
+	if !c.Append(3) {
+		t.Errorf("Expected value to be added after rotation")
+	}
+	if c.Append(3) {
+		t.Errorf("Expected duplicate value not to be added")
+	}
+	if got, expected := c.GetItems(), []int{3}; !slices.Equal(got, expected) {
+		t.Errorf("Expected items %v, but got %v", expected, got)
+	}
+}
+
+func TestBucketedRollingCache_Buckets(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		c := cache.NewBucketedRollingCache[int](10, time.Minute, 3)
+		start := time.Now().Truncate(time.Minute)
+
+		c.Append(1)
+		c.Append(2)
+		if got := c.Buckets(); len(got) != 0 {
+			t.Errorf("Expected no rotated buckets yet, but got %v", got)
+		}
+
+		time.Sleep(time.Minute)
+		c.Append(3)
+		time.Sleep(2 * time.Minute) // one interval without values
+
+		buckets := c.Buckets()
+		expected := [][]int{{1, 2}, {3}, nil}
+		if len(buckets) != len(expected) {
+			t.Fatalf("Expected %d buckets, but got %d", len(expected), len(buckets))
+		}
+		for i, b := range buckets {
+			if !slices.Equal(b.Items, expected[i]) {
+				t.Errorf("Expected bucket %d items %v, but got %v", i, expected[i], b.Items)
+			}
+			if s := start.Add(time.Duration(i) * time.Minute); !b.Start.Equal(s) || !b.End.Equal(s.Add(time.Minute)) {
+				t.Errorf("Expected bucket %d to cover [%v, %v), but got [%v, %v)", i, s, s.Add(time.Minute), b.Start, b.End)
+			}
+		}
+
+		// Only the last 3 buckets are retained
+		c.Append(4)
+		time.Sleep(time.Minute)
+		buckets = c.Buckets()
+		if len(buckets) != 3 || !slices.Equal(buckets[0].Items, []int{3}) || !slices.Equal(buckets[2].Items, []int{4}) {
+			t.Errorf("Expected buckets [3] [] [4], but got %v", buckets)
+		}
+		buckets[2].Items[0] = 100
+		if got := c.Buckets(); !slices.Equal(got[2].Items, []int{4}) {
+			t.Errorf("Expected Buckets to return copies, but got %v", got)
+		}
+		if current := c.Current(); len(current.Items) != 0 || !current.Start.Equal(start.Add(4*time.Minute)) {
+			t.Errorf("Expected an empty current bucket, but got %v", current)
+		}
+
+		// A long pause leaves only empty buckets
+		time.Sleep(time.Hour)
+		for i, b := range c.Buckets() {
+			if len(b.Items) != 0 {
+				t.Errorf("Expected bucket %d to be empty, but got %v", i, b.Items)
+			}
+		}
+	})
+}
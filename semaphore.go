@@ -0,0 +1,165 @@
+package cache
+
+import "context"
+
+// KeyedSemaphore limits the number of concurrent holders per key, for example
+// at most 3 concurrent calls per tenant to an external API.
+// Like LockManager, it keeps per-key state in a sharded map and removes a key
+// once nothing holds or waits for it.
+//
+// Waiters are served in FIFO order per key, so a large request is not starved by smaller ones.
+// Each successful Acquire or TryAcquire must be matched by a Release with the same n,
+// although the units may be released in smaller parts. A key's state holds one reference
+// per held unit and per waiter, so it is removed only once every unit has been released.
+// Acquiring or releasing zero or fewer units does nothing.
+type KeyedSemaphore[K comparable] struct {
+	sems     keyedLocks[K, semaphoreState]
+	capacity func(key K) int
+}
+
+// semaphoreState is the state of a single key in KeyedSemaphore, guarded by its shard's mutex.
+type semaphoreState struct {
+	held    int
+	waiters []semaphoreWaiter
+}
+
+// semaphoreWaiter is a goroutine waiting in Acquire. ready is closed once it has been granted n.
+type semaphoreWaiter struct {
+	n     int
+	ready chan struct{}
+}
+
+// NewKeyedSemaphore creates a new KeyedSemaphore allowing capacity concurrent holders for every key.
+func NewKeyedSemaphore[K comparable](capacity int) *KeyedSemaphore[K] {
+	return NewKeyedSemaphoreFunc(func(K) int { return capacity })
+}
+
+// NewKeyedSemaphoreFunc creates a new KeyedSemaphore whose capacity for each key is given by capacity.
+// capacity is called with internal locks held, so it must be fast and must not use the semaphore.
+func NewKeyedSemaphoreFunc[K comparable](capacity func(key K) int) *KeyedSemaphore[K] {
+	ks := &KeyedSemaphore[K]{capacity: capacity}
+	ks.sems.init(nil)
+	return ks
+}
+
+// Acquire acquires n units for key, blocking until they are available or ctx is done.
+// On failure it returns ctx.Err() and leaves the semaphore unchanged.
+// A request for more than the key's capacity blocks until ctx is done without
+// holding up other callers for key.
+func (ks *KeyedSemaphore[K]) Acquire(ctx context.Context, key K, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	l := ks.sems.acquire(key)
+	s := l.shard
+
+	s.mu.Lock()
+	st := &l.lock
+	capacity := ks.capacity(key)
+	if len(st.waiters) == 0 && st.held+n <= capacity {
+		st.held += n
+		l.refs += n - 1
+		s.mu.Unlock()
+		return nil
+	}
+	if n > capacity {
+		// The request can never be granted; queueing it would block every later waiter.
+		s.release(l)
+		s.mu.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	ready := make(chan struct{})
+	st.waiters = append(st.waiters, semaphoreWaiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-ready:
+		// Granted while giving up; hand the units back.
+		st.held -= n
+		l.refs -= n - 1
+	default:
+		i := 0
+		for st.waiters[i].ready != ready {
+			i++
+		}
+		st.waiters = append(st.waiters[:i], st.waiters[i+1:]...)
+	}
+	// Removing a waiter or units may let the next waiters in.
+	ks.grant(key, l)
+	s.release(l)
+	return ctx.Err()
+}
+
+// TryAcquire acquires n units for key without blocking and reports whether it succeeded.
+func (ks *KeyedSemaphore[K]) TryAcquire(key K, n int) bool {
+	if n <= 0 {
+		return true
+	}
+	l := ks.sems.acquire(key)
+	s := l.shard
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := &l.lock
+	if len(st.waiters) == 0 && st.held+n <= ks.capacity(key) {
+		st.held += n
+		l.refs += n - 1
+		return true
+	}
+	s.release(l)
+	return false
+}
+
+// Release releases n units for key acquired by Acquire or TryAcquire.
+// It panics if more units are released than are held.
+func (ks *KeyedSemaphore[K]) Release(key K, n int) {
+	if n <= 0 {
+		return
+	}
+	s := ks.sems.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, exists := s.locks[key]
+	if !exists || l.lock.held < n {
+		panic("cache: semaphore released more than held")
+	}
+	l.lock.held -= n
+	ks.grant(key, l)
+	l.refs -= n - 1
+	s.release(l)
+}
+
+// Size returns the number of keys that are currently held or waited for.
+func (ks *KeyedSemaphore[K]) Size() int {
+	return ks.sems.size()
+}
+
+// grant wakes waiters in FIFO order while their requests fit, turning each waiter's
+// reference into one per granted unit. The caller must hold the shard's mutex.
+func (ks *KeyedSemaphore[K]) grant(key K, l *keyLock[K, semaphoreState]) {
+	st := &l.lock
+	capacity := ks.capacity(key)
+	for len(st.waiters) > 0 {
+		w := st.waiters[0]
+		if st.held+w.n > capacity {
+			break
+		}
+		st.held += w.n
+		l.refs += w.n - 1
+		close(w.ready)
+		st.waiters[0] = semaphoreWaiter{}
+		st.waiters = st.waiters[1:]
+	}
+}
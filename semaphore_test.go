@@ -0,0 +1,194 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/catatsuy/cache"
+)
+
+func TestKeyedSemaphore_Concurrency(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ks := cache.NewKeyedSemaphore[string](3)
+
+		var current, peak atomic.Int32
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				if err := ks.Acquire(context.Background(), "tenant1", 1); err != nil {
+					t.Errorf("Acquire error: %v", err)
+					return
+				}
+				n := current.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(time.Second)
+				current.Add(-1)
+				ks.Release("tenant1", 1)
+			})
+		}
+		wg.Wait()
+
+		if p := peak.Load(); p != 3 {
+			t.Errorf("Expected at most and at least 3 concurrent holders, but got %d", p)
+		}
+		if size := ks.Size(); size != 0 {
+			t.Errorf("Expected idle keys to be removed, but got size %d", size)
+		}
+	})
+}
+
+func TestKeyedSemaphore_TryAcquire(t *testing.T) {
+	ks := cache.NewKeyedSemaphoreFunc(func(tenant string) int {
+		if tenant == "premium" {
+			return 5
+		}
+		return 2
+	})
+
+	if !ks.TryAcquire("free", 2) {
+		t.Errorf("Expected TryAcquire to succeed within capacity")
+	}
+	if ks.TryAcquire("free", 1) {
+		t.Errorf("Expected TryAcquire to fail beyond capacity")
+	}
+	if !ks.TryAcquire("premium", 5) {
+		t.Errorf("Expected per-key capacity to apply")
+	}
+
+	ks.Release("free", 2)
+	ks.Release("premium", 5)
+	if size := ks.Size(); size != 0 {
+		t.Errorf("Expected idle keys to be removed, but got size %d", size)
+	}
+}
+
+func TestKeyedSemaphore_AcquireContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ks := cache.NewKeyedSemaphore[int](3)
+		ks.Acquire(context.Background(), 1, 2)
+		ks.Acquire(context.Background(), 1, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := ks.Acquire(ctx, 1, 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, but got %v", err)
+		}
+
+		// A large waiter that gives up must not block smaller waiters behind it
+		ctx, cancel = context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			if err := ks.Acquire(ctx, 1, 2); !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, but got %v", err)
+			}
+		})
+		synctest.Wait()
+		acquired := make(chan struct{})
+		wg.Go(func() {
+			ks.Acquire(context.Background(), 1, 1)
+			close(acquired)
+		})
+		synctest.Wait()
+
+		ks.Release(1, 1) // one unit is free, but the first waiter wants two
+		synctest.Wait()
+		select {
+		case <-acquired:
+			t.Errorf("Expected the small waiter to queue behind the large one")
+		default:
+		}
+
+		cancel()
+		<-acquired
+		wg.Wait()
+
+		ks.Release(1, 1)
+		ks.Release(1, 2)
+		if size := ks.Size(); size != 0 {
+			t.Errorf("Expected idle keys to be removed, but got size %d", size)
+		}
+	})
+}
+
+func TestKeyedSemaphore_ReleaseMoreThanHeld(t *testing.T) {
+	ks := cache.NewKeyedSemaphore[int](2)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected Release without Acquire to panic")
+		}
+	}()
+	ks.Release(1, 1)
+}
+
+func TestKeyedSemaphore_PartialRelease(t *testing.T) {
+	ks := cache.NewKeyedSemaphore[int](2)
+
+	if err := ks.Acquire(context.Background(), 1, 2); err != nil {
+		t.Fatalf("Acquire error: %v", err)
+	}
+	ks.Release(1, 1)
+	if size := ks.Size(); size != 1 {
+		t.Errorf("Expected a key with a held unit to be kept, but got size %d", size)
+	}
+	if !ks.TryAcquire(1, 1) {
+		t.Errorf("Expected the released unit to be available")
+	}
+	if ks.TryAcquire(1, 1) {
+		t.Errorf("Expected the key to be at capacity")
+	}
+	ks.Release(1, 2)
+	if size := ks.Size(); size != 0 {
+		t.Errorf("Expected idle keys to be removed, but got size %d", size)
+	}
+
+	// Recycled key state must start empty
+	for key := range 100 {
+		if !ks.TryAcquire(key, 2) {
+			t.Fatalf("Expected a fresh key %d to have its full capacity", key)
+		}
+		ks.Release(key, 1)
+		ks.Release(key, 1)
+	}
+	if size := ks.Size(); size != 0 {
+		t.Errorf("Expected idle keys to be removed, but got size %d", size)
+	}
+}
+
+func TestKeyedSemaphore_AcquireOverCapacity(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ks := cache.NewKeyedSemaphore[string](3)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errc := make(chan error)
+		go func() {
+			errc <- ks.Acquire(ctx, "t", 4)
+		}()
+		synctest.Wait()
+
+		if !ks.TryAcquire("t", 1) {
+			t.Errorf("Expected TryAcquire to succeed while an oversized request is pending")
+		}
+		if err := ks.Acquire(context.Background(), "t", 2); err != nil {
+			t.Errorf("Acquire error: %v", err)
+		}
+		ks.Release("t", 3)
+
+		cancel()
+		if err := <-errc; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, but got %v", err)
+		}
+		if size := ks.Size(); size != 0 {
+			t.Errorf("Expected idle keys to be removed, but got size %d", size)
+		}
+	})
+}
@@ -1,58 +1,227 @@
 package cache
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
 	"sync"
+	"time"
 )
 
-// SingleflightGroup manages single concurrent requests per key, ensuring that
+// SingleflightGroupKeyed manages single concurrent requests per key, ensuring that
 // only one execution of a function occurs for a given key at a time.
+// Keys can be any comparable type, so integer or struct keys are used directly
+// instead of being formatted into strings.
 //
-// This implementation is simplified compared to the official singleflight package
-// and lacks advanced error handling and other features, such as:
-//   - Panic and runtime.Goexit handling: This implementation does not handle cases
-//     where the function panics or terminates abnormally. In the official implementation,
-//     errors from panic and Goexit are handled to prevent blocked goroutines.
-//   - Shared result indicator: The official singleflight implementation includes a boolean
-//     return value to indicate if the result was shared among multiple callers. This
-//     implementation does not include this feature.
-//   - Immediate synchronous cleanup: In this implementation, the completed result is
-//     removed from the map asynchronously. In the official implementation, cleanup
-//     is handled synchronously within the doCall function to ensure immediate memory release.
-type SingleflightGroup[V any] struct {
-	mu sync.Mutex
-	m  map[string]*call[V]
+// If fn panics, the panic is propagated to the caller that ran fn and to every
+// caller waiting for the same key. If fn calls runtime.Goexit, the key is not
+// poisoned: the next waiting caller runs its own fn instead.
+//
+// By default a completed call is removed from the map by a background goroutine,
+// which keeps Do fast but means that a Do starting shortly after another one returned
+// may still receive its result instead of running fn. The official singleflight removes
+// the call synchronously before returning; use WithSyncCleanup for the same behavior,
+// or Forget to force the next Do for a key to run fn.
+type SingleflightGroupKeyed[K comparable, V any] struct {
+	mu          sync.Mutex
+	m           map[K]*call[V]
+	ctxCalls    map[K]*ctxCall[V] // calls of DoContext, created on first use
+	syncCleanup bool
+	successTTL  time.Duration
+	failureTTL  time.Duration
+}
+
+// SingleflightOption configures SingleflightGroup and SingleflightGroupKeyed.
+type SingleflightOption func(*singleflightConfig)
+
+type singleflightConfig struct {
+	syncCleanup bool
+	successTTL  time.Duration
+	failureTTL  time.Duration
+}
+
+// WithSyncCleanup removes a completed call from the map before fn's caller returns,
+// so any Do that starts after a Do for the same key has returned runs fn again.
+// Callers already waiting for the call still receive its result.
+func WithSyncCleanup() SingleflightOption {
+	return func(c *singleflightConfig) {
+		c.syncCleanup = true
+	}
+}
+
+// SingleflightGroup is a SingleflightGroupKeyed with string keys.
+type SingleflightGroup[V any] = SingleflightGroupKeyed[string, V]
+
+// WithResultTTL keeps the result of a completed call for success or failure after fn
+// returns, depending on whether fn returned a nil error, so callers arriving within that
+// window reuse it instead of running fn again. This turns the group into a short-lived
+// cache that also absorbs sequential bursts for hot keys. A zero TTL keeps the default
+// cleanup for that kind of result, and results of a panicking fn are never kept.
+// It applies to Do, DoShared and DoChan; Forget discards a kept result early.
+func WithResultTTL(success, failure time.Duration) SingleflightOption {
+	return func(c *singleflightConfig) {
+		c.successTTL = success
+		c.failureTTL = failure
+	}
 }
 
 // call represents a single execution result for a specific key, holding the
 // value, any error encountered, and whether the execution is completed.
+// If fn panicked, err is a *panicError.
 type call[V any] struct {
 	mu    sync.Mutex
 	value V
 	err   error
 	done  bool
+
+	// dups counts the callers that found the call in the map, and expires is when
+	// a result kept by WithResultTTL stops being reused. They are guarded by the
+	// group's mutex rather than mu, which is held while fn runs.
+	dups    int
+	expires time.Time
+}
+
+// ctxCall is an execution of DoContext, running on its own goroutine.
+type ctxCall[V any] struct {
+	done   chan struct{} // closed once value and err are set
+	value  V
+	err    error
+	cancel context.CancelFunc
+
+	// refs counts the callers still waiting for the result. It is guarded by the group's mutex.
+	refs int
+}
+
+// Result holds the results of DoChan, so they can be passed on a channel.
+type Result[V any] struct {
+	Val    V
+	Err    error
+	Shared bool
+}
+
+// errGoexit is returned by DoChan and DoContext when fn calls runtime.Goexit.
+var errGoexit = errors.New("runtime.Goexit was called")
+
+// panicError is the value Do panics with when fn panics, holding the original
+// panic value and the stack trace of the goroutine that ran fn.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (p *panicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+func (p *panicError) Unwrap() error {
+	err, ok := p.value.(error)
+	if !ok {
+		return nil
+	}
+	return err
+}
+
+func newPanicError(v any) error {
+	stack := debug.Stack()
+
+	// The first line of the stack trace is of the form "goroutine N [status]:"
+	// but by the time the panic reaches Do the goroutine may no longer exist
+	// and its status will have changed. Trim out the misleading line.
+	if line := bytes.IndexByte(stack, '\n'); line >= 0 {
+		stack = stack[line+1:]
+	}
+	return &panicError{value: v, stack: stack}
 }
 
 // NewSingleflightGroup creates a new instance of SingleflightGroup, initialized
 // with an empty map to store calls by key.
-func NewSingleflightGroup[V any]() *SingleflightGroup[V] {
-	return &SingleflightGroup[V]{
-		m: make(map[string]*call[V]),
+func NewSingleflightGroup[V any](opts ...SingleflightOption) *SingleflightGroup[V] {
+	return NewSingleflightGroupKeyed[string, V](opts...)
+}
+
+// NewSingleflightGroupKeyed creates a new instance of SingleflightGroupKeyed, initialized
+// with an empty map to store calls by key.
+func NewSingleflightGroupKeyed[K comparable, V any](opts ...SingleflightOption) *SingleflightGroupKeyed[K, V] {
+	var c singleflightConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &SingleflightGroupKeyed[K, V]{
+		m:           make(map[K]*call[V]),
+		syncCleanup: c.syncCleanup,
+		successTTL:  c.successTTL,
+		failureTTL:  c.failureTTL,
 	}
 }
 
 // Do ensures that for a given key, only one execution of fn occurs at a time.
 // If a call for the key is already in progress, other calls wait for its completion
 // and return the same result. Once complete, the result is stored and used for
-// subsequent calls until it's removed from the map.
+// subsequent calls until it's removed from the map, which happens asynchronously
+// unless the group was created with WithSyncCleanup.
 //
-// Unlike the official singleflight, this function does not provide:
-//   - Panic and Goexit handling
-//   - Shared result flag to indicate if the result was reused for multiple callers
-func (sf *SingleflightGroup[V]) Do(key string, fn func() (V, error)) (V, error) {
+// If fn panics, Do panics in the calling goroutine and in all waiting goroutines
+// with an error that wraps the original panic value and includes its stack trace.
+// Use DoShared to also learn whether the result was given to multiple callers.
+func (sf *SingleflightGroupKeyed[K, V]) Do(key K, fn func() (V, error)) (V, error) {
+	c, _ := sf.do(key, fn)
+	return c.value, c.err
+}
+
+// DoShared is like Do but also reports whether the result was given to multiple callers.
+func (sf *SingleflightGroupKeyed[K, V]) DoShared(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	c, found := sf.do(key, fn)
+	shared = found
+	if !found {
+		// Callers may have joined while this one was running fn.
+		sf.mu.Lock()
+		shared = c.dups > 0
+		sf.mu.Unlock()
+	}
+	return c.value, c.err, shared
+}
+
+// DoChan is like DoShared but returns a channel that receives the result when it is ready,
+// so the caller can wait for it alongside a timeout or other channels.
+// The channel is buffered, so DoChan does not leak a goroutine if the result is never received.
+//
+// fn runs on a goroutine started by DoChan, so if fn panics the panic cannot be
+// recovered by the caller and crashes the program.
+func (sf *SingleflightGroupKeyed[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
+	ch := make(chan Result[V], 1)
+	go func() {
+		sent := false
+		defer func() {
+			if !sent {
+				// fn called runtime.Goexit.
+				ch <- Result[V]{Err: errGoexit}
+			}
+		}()
+
+		v, err, shared := sf.DoShared(key, fn)
+		ch <- Result[V]{Val: v, Err: err, Shared: shared}
+		sent = true
+	}()
+	return ch
+}
+
+// do runs fn for key unless a call for key is in progress or completed, and returns
+// the call holding the result and whether it was found in the map.
+// It panics if fn panicked.
+func (sf *SingleflightGroupKeyed[K, V]) do(key K, fn func() (V, error)) (c *call[V], found bool) {
 	// Lock to check if a call is already in progress for the given key
 	sf.mu.Lock()
-	c, ok := sf.m[key]
-	if !ok {
+	c, found = sf.m[key]
+	if found && !c.expires.IsZero() && !time.Now().Before(c.expires) {
+		// The kept result has expired but its timer has not removed it yet.
+		found = false
+	}
+	if found {
+		c.dups++
+	} else {
 		// If no call exists for the key, create a new one
 		c = &call[V]{}
 		sf.m[key] = c
@@ -63,20 +232,172 @@ func (sf *SingleflightGroup[V]) Do(key string, fn func() (V, error)) (V, error)
 	c.mu.Lock()
 	if !c.done {
 		// If fn has not been executed, run it and store the result
-		c.value, c.err = fn()
+		sf.doCall(c, key, fn)
+	}
+	c.mu.Unlock()
+
+	if e, ok := c.err.(*panicError); ok {
+		panic(e)
+	}
+	return c, found
+}
+
+// doCall runs fn and stores its result in c. The caller must hold c.mu.
+// A panic in fn is recovered and stored in c.err for Do to re-panic. If fn calls
+// runtime.Goexit, c is removed and c.mu is unlocked without marking c done, so the next waiter runs fn.
+func (sf *SingleflightGroupKeyed[K, V]) doCall(c *call[V], key K, fn func() (V, error)) {
+	normalReturn := false
+	recovered := false
+
+	// Use a double defer to distinguish a panic from runtime.Goexit.
+	defer func() {
+		if !normalReturn && !recovered {
+			// fn called runtime.Goexit, so Do never gets to unlock c.mu.
+			// Later callers start a new call; those already holding c run fn themselves.
+			sf.remove(key, c)
+			c.mu.Unlock()
+			return
+		}
 		c.done = true
 
-		// Schedule the deletion of the completed call asynchronously
-		go func() {
+		if ttl := sf.resultTTL(c.err); ttl > 0 {
 			sf.mu.Lock()
-			// Only delete if the call in the map is the same as the completed one
-			if sf.m[key] == c {
-				delete(sf.m, key)
-			}
+			c.expires = time.Now().Add(ttl)
 			sf.mu.Unlock()
+			time.AfterFunc(ttl, func() {
+				sf.remove(key, c)
+			})
+			return
+		}
+		if sf.syncCleanup {
+			// Remove the call while holding c.mu, so it is gone before any caller returns
+			sf.remove(key, c)
+			return
+		}
+		// Schedule the deletion of the completed call asynchronously
+		go sf.remove(key, c)
+	}()
+
+	func() {
+		defer func() {
+			if !normalReturn {
+				// recover returns nil for runtime.Goexit.
+				if r := recover(); r != nil {
+					c.err = newPanicError(r)
+				}
+			}
 		}()
+
+		c.value, c.err = fn()
+		normalReturn = true
+	}()
+
+	if !normalReturn {
+		recovered = true
 	}
-	c.mu.Unlock()
+}
 
-	return c.value, c.err
+// resultTTL returns how long a result with err is kept after fn returns.
+func (sf *SingleflightGroupKeyed[K, V]) resultTTL(err error) time.Duration {
+	if err == nil {
+		return sf.successTTL
+	}
+	if _, ok := err.(*panicError); ok {
+		return 0
+	}
+	return sf.failureTTL
+}
+
+// Forget tells the group to forget about a key, so that the next Do or DoContext for it
+// runs fn rather than waiting for or reusing an earlier call.
+// Callers already waiting for the earlier call still receive its result.
+func (sf *SingleflightGroupKeyed[K, V]) Forget(key K) {
+	sf.mu.Lock()
+	delete(sf.m, key)
+	delete(sf.ctxCalls, key)
+	sf.mu.Unlock()
+}
+
+// remove deletes the completed call c from the map.
+func (sf *SingleflightGroupKeyed[K, V]) remove(key K, c *call[V]) {
+	sf.mu.Lock()
+	// Only delete if the call in the map is the same as the completed one
+	if sf.m[key] == c {
+		delete(sf.m, key)
+	}
+	sf.mu.Unlock()
+}
+
+// DoContext is like Do but lets each caller stop waiting when its ctx is done,
+// in which case it returns ctx.Err() while the call keeps running for the other callers.
+// fn runs on its own goroutine with a context that carries the values of the first
+// caller's ctx but not its deadline, and that is cancelled once every caller has given up,
+// so fn can stop work whose result is no longer wanted.
+//
+// Calls of DoContext and Do do not share results even for the same key.
+// The call is removed as soon as fn returns, so a later DoContext runs fn again.
+// If fn panics, DoContext panics in all callers still waiting.
+func (sf *SingleflightGroupKeyed[K, V]) DoContext(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+	sf.mu.Lock()
+	if sf.ctxCalls == nil {
+		sf.ctxCalls = make(map[K]*ctxCall[V])
+	}
+	c, ok := sf.ctxCalls[key]
+	if !ok {
+		fnCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &ctxCall[V]{done: make(chan struct{}), cancel: cancel}
+		sf.ctxCalls[key] = c
+		go sf.doContextCall(fnCtx, key, c, fn)
+	}
+	c.refs++
+	sf.mu.Unlock()
+
+	select {
+	case <-c.done:
+		if e, ok := c.err.(*panicError); ok {
+			panic(e)
+		}
+		return c.value, c.err
+	case <-ctx.Done():
+	}
+
+	sf.mu.Lock()
+	c.refs--
+	if c.refs == 0 {
+		// Nobody wants the result anymore; later callers start a new call.
+		c.cancel()
+		if sf.ctxCalls[key] == c {
+			delete(sf.ctxCalls, key)
+		}
+	}
+	sf.mu.Unlock()
+
+	var zero V
+	return zero, ctx.Err()
+}
+
+// doContextCall runs fn for c and removes c from the map before publishing the result.
+func (sf *SingleflightGroupKeyed[K, V]) doContextCall(ctx context.Context, key K, c *ctxCall[V], fn func(ctx context.Context) (V, error)) {
+	normalReturn := false
+	defer func() {
+		if !normalReturn {
+			if r := recover(); r != nil {
+				c.err = newPanicError(r)
+			} else {
+				c.err = errGoexit
+			}
+		}
+
+		sf.mu.Lock()
+		if sf.ctxCalls[key] == c {
+			delete(sf.ctxCalls, key)
+		}
+		sf.mu.Unlock()
+
+		c.cancel()
+		close(c.done)
+	}()
+
+	c.value, c.err = fn(ctx)
+	normalReturn = true
 }
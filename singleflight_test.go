@@ -1,10 +1,15 @@
 package cache_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/catatsuy/cache"
@@ -131,3 +136,552 @@ func TestDoMultipleErrors(t *testing.T) {
 		t.Errorf("number of calls = %d; want 1", got)
 	}
 }
+
+func TestDoPanic(t *testing.T) {
+	sf := cache.NewSingleflightGroup[string]()
+	someErr := errors.New("Some error")
+
+	var recovered any
+	func() {
+		defer func() {
+			recovered = recover()
+		}()
+		sf.Do("key", func() (string, error) {
+			panic(someErr)
+		})
+	}()
+
+	err, ok := recovered.(error)
+	if !ok {
+		t.Fatalf("recovered %T %v; want an error", recovered, recovered)
+	}
+	if !errors.Is(err, someErr) {
+		t.Errorf("recovered error %v; want it to wrap %v", err, someErr)
+	}
+	if !strings.Contains(err.Error(), "singleflight_test.go") {
+		t.Errorf("recovered error does not contain the stack of fn: %v", err)
+	}
+}
+
+func TestDoPanicWaiters(t *testing.T) {
+	sf := cache.NewSingleflightGroup[string]()
+	release := make(chan struct{})
+	var calls, panics int32
+
+	const n = 10
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil && strings.Contains(fmt.Sprint(r), "boom") {
+					atomic.AddInt32(&panics, 1)
+				}
+			}()
+			sf.Do("key", func() (string, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				panic("boom")
+			})
+			t.Errorf("Do returned; want a panic")
+		}()
+	}
+	time.Sleep(10 * time.Millisecond) // let the goroutines enter Do
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&panics); got != n {
+		t.Errorf("number of panics = %d; want %d", got, n)
+	}
+	if got := atomic.LoadInt32(&calls); got <= 0 || got >= n {
+		t.Errorf("number of calls = %d; want over 0 and less than %d", got, n)
+	}
+}
+
+func TestDoGoexit(t *testing.T) {
+	sf := cache.NewSingleflightGroup[string]()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	go func() {
+		sf.Do("key", func() (string, error) {
+			close(entered)
+			<-release
+			runtime.Goexit()
+			return "", nil
+		})
+		t.Errorf("Do returned after runtime.Goexit")
+	}()
+	<-entered
+
+	var calls int32
+	const n = 10
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := sf.Do("key", func() (string, error) {
+				atomic.AddInt32(&calls, 1)
+				return "bar", nil
+			})
+			if v != "bar" || err != nil {
+				t.Errorf("Do = %q, %v; want bar, nil", v, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond) // let the goroutines wait for the first call
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got <= 0 || got >= n {
+		t.Errorf("number of calls = %d; want over 0 and less than %d", got, n)
+	}
+
+	// A call ended by runtime.Goexit with no waiters must not be left for a later caller to share
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		sf.Do("other", func() (string, error) {
+			runtime.Goexit()
+			return "", nil
+		})
+	}()
+	<-exited
+	v, err, shared := sf.DoShared("other", func() (string, error) {
+		return "bar", nil
+	})
+	if v != "bar" || err != nil {
+		t.Errorf("DoShared = %q, %v; want bar, nil", v, err)
+	}
+	if shared {
+		t.Errorf("DoShared reported a result after runtime.Goexit as shared")
+	}
+}
+
+func TestDoShared(t *testing.T) {
+	sf := cache.NewSingleflightGroup[string]()
+	v, err, shared := sf.DoShared("key", func() (string, error) {
+		return "bar", nil
+	})
+	if v != "bar" || err != nil {
+		t.Errorf("DoShared = %q, %v; want bar, nil", v, err)
+	}
+	if shared {
+		t.Errorf("DoShared reported a result with a single caller as shared")
+	}
+}
+
+func TestDoSharedDupSuppress(t *testing.T) {
+	sf := cache.NewSingleflightGroup[string]()
+	release := make(chan struct{})
+	var calls, sharedCount int32
+
+	const n = 10
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := sf.DoShared("key", func() (string, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "bar", nil
+			})
+			if v != "bar" || err != nil {
+				t.Errorf("DoShared = %q, %v; want bar, nil", v, err)
+			}
+			if shared {
+				atomic.AddInt32(&sharedCount, 1)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond) // let the goroutines enter DoShared
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("number of calls = %d; want 1", got)
+	}
+	if got := atomic.LoadInt32(&sharedCount); got != n {
+		t.Errorf("number of shared results = %d; want %d", got, n)
+	}
+}
+
+func TestDoChan(t *testing.T) {
+	sf := cache.NewSingleflightGroup[string]()
+	release := make(chan struct{})
+
+	ch1 := sf.DoChan("key", func() (string, error) {
+		<-release
+		return "bar", nil
+	})
+	time.Sleep(10 * time.Millisecond) // let the first call start
+	ch2 := sf.DoChan("key", func() (string, error) {
+		t.Errorf("fn executed twice")
+		return "", nil
+	})
+
+	select {
+	case res := <-ch1:
+		t.Fatalf("DoChan delivered %+v before fn returned", res)
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+
+	for _, ch := range []<-chan cache.Result[string]{ch1, ch2} {
+		res := <-ch
+		if res.Val != "bar" || res.Err != nil || !res.Shared {
+			t.Errorf("DoChan = %+v; want shared bar", res)
+		}
+	}
+}
+
+func TestDoChanGoexit(t *testing.T) {
+	sf := cache.NewSingleflightGroup[string]()
+	res := <-sf.DoChan("key", func() (string, error) {
+		runtime.Goexit()
+		return "bar", nil
+	})
+	if res.Err == nil {
+		t.Errorf("DoChan = %+v; want an error", res)
+	}
+}
+
+func TestDoKeyed(t *testing.T) {
+	type key struct {
+		table string
+		id    int
+	}
+	sf := cache.NewSingleflightGroupKeyed[key, int]()
+
+	v, err := sf.Do(key{"users", 1}, func() (int, error) {
+		return 1, nil
+	})
+	if v != 1 || err != nil {
+		t.Errorf("Do = %d, %v; want 1, nil", v, err)
+	}
+
+	release := make(chan struct{})
+	ch := sf.DoChan(key{"users", 2}, func() (int, error) {
+		<-release
+		return 2, nil
+	})
+	ch2 := sf.DoChan(key{"posts", 2}, func() (int, error) {
+		return 3, nil
+	})
+	if res := <-ch2; res.Val != 3 {
+		t.Errorf("DoChan = %+v; want 3 for a different key", res)
+	}
+	close(release)
+	if res := <-ch; res.Val != 2 {
+		t.Errorf("DoChan = %+v; want 2", res)
+	}
+}
+
+func TestDoContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sf := cache.NewSingleflightGroup[string]()
+		var calls int32
+		fn := func(ctx context.Context) (string, error) {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(time.Second)
+			return "bar", nil
+		}
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				v, err := sf.DoContext(t.Context(), "key", fn)
+				if v != "bar" || err != nil {
+					t.Errorf("DoContext = %q, %v; want bar, nil", v, err)
+				}
+			})
+		}
+		wg.Wait()
+
+		if got := atomic.LoadInt32(&calls); got != 1 {
+			t.Errorf("number of calls = %d; want 1", got)
+		}
+
+		// The call is removed once fn returns.
+		sf.DoContext(t.Context(), "key", fn)
+		if got := atomic.LoadInt32(&calls); got != 2 {
+			t.Errorf("number of calls = %d; want 2", got)
+		}
+	})
+}
+
+func TestDoContextWaiterGivesUp(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sf := cache.NewSingleflightGroup[string]()
+		fn := func(ctx context.Context) (string, error) {
+			select {
+			case <-time.After(time.Second):
+				return "bar", nil
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+			defer cancel()
+			if _, err := sf.DoContext(ctx, "key", fn); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("DoContext error = %v; want %v", err, context.DeadlineExceeded)
+			}
+		})
+		wg.Go(func() {
+			v, err := sf.DoContext(t.Context(), "key", fn)
+			if v != "bar" || err != nil {
+				t.Errorf("DoContext = %q, %v; want bar, nil", v, err)
+			}
+		})
+		wg.Wait()
+	})
+}
+
+func TestDoContextCancelsAbandonedCall(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sf := cache.NewSingleflightGroup[string]()
+		fnErr := make(chan error, 1)
+		fn := func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			fnErr <- ctx.Err()
+			return "", ctx.Err()
+		}
+
+		ctx1, cancel1 := context.WithCancel(t.Context())
+		ctx2, cancel2 := context.WithCancel(t.Context())
+		var wg sync.WaitGroup
+		for _, ctx := range []context.Context{ctx1, ctx2} {
+			wg.Go(func() {
+				if _, err := sf.DoContext(ctx, "key", fn); !errors.Is(err, context.Canceled) {
+					t.Errorf("DoContext error = %v; want %v", err, context.Canceled)
+				}
+			})
+		}
+		synctest.Wait()
+
+		cancel1()
+		synctest.Wait()
+		select {
+		case err := <-fnErr:
+			t.Fatalf("fn context done with %v while a caller is still waiting", err)
+		default:
+		}
+
+		cancel2()
+		wg.Wait()
+		if err := <-fnErr; !errors.Is(err, context.Canceled) {
+			t.Errorf("fn context error = %v; want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestDoContextPanic(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sf := cache.NewSingleflightGroup[string]()
+		var panics int32
+
+		var wg sync.WaitGroup
+		for range 3 {
+			wg.Go(func() {
+				defer func() {
+					if r := recover(); r != nil && strings.Contains(fmt.Sprint(r), "boom") {
+						atomic.AddInt32(&panics, 1)
+					}
+				}()
+				sf.DoContext(t.Context(), "key", func(ctx context.Context) (string, error) {
+					time.Sleep(time.Second)
+					panic("boom")
+				})
+			})
+		}
+		wg.Wait()
+
+		if got := atomic.LoadInt32(&panics); got != 3 {
+			t.Errorf("number of panics = %d; want 3", got)
+		}
+	})
+}
+
+func TestDoCleanup(t *testing.T) {
+	counter := func() (func() (int, error), *int32) {
+		var calls int32
+		return func() (int, error) {
+			return int(atomic.AddInt32(&calls, 1)), nil
+		}, &calls
+	}
+
+	t.Run("async", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			sf := cache.NewSingleflightGroup[int]()
+			fn, calls := counter()
+
+			sf.Do("key", fn)
+			synctest.Wait() // let the background goroutine remove the call
+			if v, _ := sf.Do("key", fn); v != 2 {
+				t.Errorf("Do after the call was removed = %d; want fn to run again", v)
+			}
+			if got := atomic.LoadInt32(calls); got != 2 {
+				t.Errorf("number of calls = %d; want 2", got)
+			}
+		})
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		sf := cache.NewSingleflightGroup[int](cache.WithSyncCleanup())
+		fn, calls := counter()
+
+		for i := 1; i <= 3; i++ {
+			if v, _ := sf.Do("key", fn); v != i {
+				t.Errorf("Do = %d; want %d", v, i)
+			}
+		}
+		if got := atomic.LoadInt32(calls); got != 3 {
+			t.Errorf("number of calls = %d; want 3", got)
+		}
+	})
+
+	t.Run("forget", func(t *testing.T) {
+		sf := cache.NewSingleflightGroup[int]()
+		fn, calls := counter()
+
+		sf.Do("key", fn)
+		sf.Forget("key")
+		if v, _ := sf.Do("key", fn); v != 2 {
+			t.Errorf("Do after Forget = %d; want 2", v)
+		}
+		if got := atomic.LoadInt32(calls); got != 2 {
+			t.Errorf("number of calls = %d; want 2", got)
+		}
+	})
+}
+
+func TestDoSyncCleanupDupSuppress(t *testing.T) {
+	sf := cache.NewSingleflightGroup[string](cache.WithSyncCleanup())
+	release := make(chan struct{})
+	var calls int32
+
+	const n = 10
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			v, err := sf.Do("key", func() (string, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "bar", nil
+			})
+			if v != "bar" || err != nil {
+				t.Errorf("Do = %q, %v; want bar, nil", v, err)
+			}
+		})
+	}
+	time.Sleep(10 * time.Millisecond) // let the goroutines enter Do
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("number of calls = %d; want 1", got)
+	}
+}
+
+func TestForgetInFlight(t *testing.T) {
+	sf := cache.NewSingleflightGroup[string]()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	first := sf.DoChan("key", func() (string, error) {
+		close(entered)
+		<-release
+		return "first", nil
+	})
+	<-entered
+
+	sf.Forget("key")
+	v, err := sf.Do("key", func() (string, error) {
+		return "second", nil
+	})
+	if v != "second" || err != nil {
+		t.Errorf("Do after Forget = %q, %v; want second, nil", v, err)
+	}
+
+	close(release)
+	if res := <-first; res.Val != "first" {
+		t.Errorf("in-flight call = %+v; want first", res)
+	}
+}
+
+func TestDoResultTTL(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sf := cache.NewSingleflightGroup[int](cache.WithSyncCleanup(), cache.WithResultTTL(100*time.Millisecond, 10*time.Millisecond))
+		var calls int32
+		fn := func() (int, error) {
+			return int(atomic.AddInt32(&calls, 1)), nil
+		}
+
+		sf.Do("key", fn)
+		time.Sleep(99 * time.Millisecond)
+		v, _, shared := sf.DoShared("key", fn)
+		if v != 1 || !shared {
+			t.Errorf("DoShared within the TTL = %d, shared %v; want the kept result 1", v, shared)
+		}
+		time.Sleep(time.Millisecond)
+		if v, _ := sf.Do("key", fn); v != 2 {
+			t.Errorf("Do after the TTL = %d; want fn to run again", v)
+		}
+
+		sf.Forget("key")
+		if v, _ := sf.Do("key", fn); v != 3 {
+			t.Errorf("Do after Forget = %d; want fn to run again", v)
+		}
+	})
+}
+
+func TestDoResultTTLFailure(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		sf := cache.NewSingleflightGroup[int](cache.WithSyncCleanup(), cache.WithResultTTL(time.Second, 10*time.Millisecond))
+		someErr := errors.New("Some error")
+		var calls int32
+		fn := func() (int, error) {
+			atomic.AddInt32(&calls, 1)
+			return 0, someErr
+		}
+
+		sf.Do("key", fn)
+		time.Sleep(5 * time.Millisecond)
+		if _, err := sf.Do("key", fn); err != someErr {
+			t.Errorf("Do error = %v; want %v", err, someErr)
+		}
+		if got := atomic.LoadInt32(&calls); got != 1 {
+			t.Errorf("number of calls = %d; want 1 within the failure TTL", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+		sf.Do("key", fn)
+		if got := atomic.LoadInt32(&calls); got != 2 {
+			t.Errorf("number of calls = %d; want 2 after the failure TTL", got)
+		}
+	})
+}
+
+func TestDoResultTTLPanic(t *testing.T) {
+	sf := cache.NewSingleflightGroup[int](cache.WithSyncCleanup(), cache.WithResultTTL(time.Hour, time.Hour))
+	func() {
+		defer func() {
+			recover()
+		}()
+		sf.Do("key", func() (int, error) {
+			panic("boom")
+		})
+	}()
+
+	v, err := sf.Do("key", func() (int, error) {
+		return 1, nil
+	})
+	if v != 1 || err != nil {
+		t.Errorf("Do after a panic = %d, %v; want fn to run again", v, err)
+	}
+}
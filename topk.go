@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+)
+
+// Pair is a key-value pair returned by TopK.
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// topKTracker keeps the k keys with the largest values of an integer cache in a min-heap.
+// It is not safe for concurrent use; the owning cache guards it with its own lock.
+//
+// Increases only need to be compared against the heap minimum, so they cost O(log k).
+// When a tracked key decreases or is deleted, an untracked key may have to take its
+// place, so the tracker is marked dirty and rebuilt from the cache items in O(n log k)
+// by the next top. Writes in between cost O(1), so resetting many counters does not
+// rebuild the heap once per write.
+type topKTracker[K comparable, V cmp.Ordered] struct {
+	k     int
+	items []Pair[K, V]
+	index map[K]int // position of each tracked key in items
+	dirty bool      // items must be rebuilt before use
+}
+
+func newTopKTracker[K comparable, V cmp.Ordered](k int, items map[K]V) *topKTracker[K, V] {
+	t := &topKTracker[K, V]{k: k}
+	t.reset(items)
+	return t
+}
+
+func (t *topKTracker[K, V]) Len() int           { return len(t.items) }
+func (t *topKTracker[K, V]) Less(i, j int) bool { return t.items[i].Value < t.items[j].Value }
+
+func (t *topKTracker[K, V]) Swap(i, j int) {
+	t.items[i], t.items[j] = t.items[j], t.items[i]
+	t.index[t.items[i].Key] = i
+	t.index[t.items[j].Key] = j
+}
+
+func (t *topKTracker[K, V]) Push(x any) {
+	p := x.(Pair[K, V])
+	t.index[p.Key] = len(t.items)
+	t.items = append(t.items, p)
+}
+
+func (t *topKTracker[K, V]) Pop() any {
+	p := t.items[len(t.items)-1]
+	t.items = t.items[:len(t.items)-1]
+	delete(t.index, p.Key)
+	return p
+}
+
+// reset rebuilds the heap from items.
+func (t *topKTracker[K, V]) reset(items map[K]V) {
+	t.dirty = false
+	t.items = make([]Pair[K, V], 0, t.k)
+	t.index = make(map[K]int, t.k)
+	for key, value := range items {
+		t.offer(key, value)
+	}
+}
+
+// offer considers an untracked key for a place in the heap.
+func (t *topKTracker[K, V]) offer(key K, value V) {
+	if t.k <= 0 {
+		return
+	}
+	if len(t.items) < t.k {
+		heap.Push(t, Pair[K, V]{Key: key, Value: value})
+		return
+	}
+	if value > t.items[0].Value {
+		delete(t.index, t.items[0].Key)
+		t.items[0] = Pair[K, V]{Key: key, Value: value}
+		t.index[key] = 0
+		heap.Fix(t, 0)
+	}
+}
+
+// update records that key now has value.
+func (t *topKTracker[K, V]) update(key K, value V) {
+	if t.dirty {
+		return
+	}
+	i, tracked := t.index[key]
+	if !tracked {
+		t.offer(key, value)
+		return
+	}
+	if value < t.items[i].Value {
+		t.dirty = true
+		return
+	}
+	t.items[i].Value = value
+	heap.Fix(t, i)
+}
+
+// remove records that key has been deleted from the cache items.
+func (t *topKTracker[K, V]) remove(key K) {
+	if _, tracked := t.index[key]; tracked {
+		t.dirty = true
+	}
+}
+
+// top returns up to n tracked pairs ordered by descending value,
+// first rebuilding the heap from items if it is dirty.
+func (t *topKTracker[K, V]) top(n int, items map[K]V) []Pair[K, V] {
+	if t.dirty {
+		t.reset(items)
+	}
+	pairs := slices.Clone(t.items)
+	slices.SortFunc(pairs, func(a, b Pair[K, V]) int {
+		return cmp.Compare(b.Value, a.Value)
+	})
+	if n < len(pairs) {
+		pairs = pairs[:max(n, 0)]
+	}
+	return pairs
+}
@@ -0,0 +1,110 @@
+package cache_test
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/catatsuy/cache"
+)
+
+func TestWriteHeavyCacheInteger_TopK(t *testing.T) {
+	c := cache.NewWriteHeavyCacheInteger[string, int]()
+	c.Set("a", 5)
+	c.Set("b", 1)
+	c.EnableTopK(2)
+
+	c.Incr("c", 3)
+	c.Incr("b", 10)
+
+	expected := []cache.Pair[string, int]{{Key: "b", Value: 11}, {Key: "a", Value: 5}}
+	if got := c.TopK(5); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, but got %v", expected, got)
+	}
+
+	// Decreasing a tracked key lets an untracked key take its place
+	c.Incr("b", -10)
+	expected = []cache.Pair[string, int]{{Key: "a", Value: 5}, {Key: "c", Value: 3}}
+	if got := c.TopK(2); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, but got %v", expected, got)
+	}
+
+	c.Delete("a")
+	expected = []cache.Pair[string, int]{{Key: "c", Value: 3}}
+	if got := c.TopK(1); !slices.Equal(got, expected) {
+		t.Errorf("Expected %v, but got %v", expected, got)
+	}
+
+	c.Clear()
+	if got := c.TopK(2); len(got) != 0 {
+		t.Errorf("Expected no pairs after Clear, but got %v", got)
+	}
+}
+
+func TestReadHeavyCacheInteger_TopK(t *testing.T) {
+	c := cache.NewReadHeavyCacheInteger[int, int]()
+	if got := c.TopK(3); got != nil {
+		t.Errorf("Expected nil before EnableTopK, but got %v", got)
+	}
+	c.EnableTopK(3)
+
+	// Compare with a full sort after random updates
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 10000 {
+		key := r.IntN(100)
+		switch r.IntN(10) {
+		case 0:
+			c.Delete(key)
+		case 1:
+			c.Set(key, r.IntN(50))
+		default:
+			c.Incr(key, r.IntN(20)-5)
+		}
+	}
+
+	values := make([]int, 0, c.Size())
+	for _, v := range c.GetItems() {
+		values = append(values, v)
+	}
+	slices.Sort(values)
+	slices.Reverse(values)
+
+	got := c.TopK(3)
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 pairs, but got %d", len(got))
+	}
+	for i, p := range got {
+		if p.Value != values[i] {
+			t.Errorf("Expected TopK[%d] value %d, but got %d", i, values[i], p.Value)
+		}
+		if v, _ := c.Get(p.Key); v != p.Value {
+			t.Errorf("Expected value %d for key %d, but got %d", v, p.Key, p.Value)
+		}
+	}
+}
+
+func TestWriteHeavyCacheInteger_TopKResetCounters(t *testing.T) {
+	c := cache.NewWriteHeavyCacheInteger[int, int]()
+	c.EnableTopK(2)
+
+	for round := range 3 {
+		for key := range 10 {
+			c.Incr(key, key+round)
+		}
+		expected := []cache.Pair[int, int]{{Key: 9, Value: 9 + round}, {Key: 8, Value: 8 + round}}
+		if got := c.TopK(2); !slices.Equal(got, expected) {
+			t.Errorf("round %d: Expected %v, but got %v", round, expected, got)
+		}
+
+		// Reset every counter after reporting, then let a single key lead
+		for key := range 10 {
+			c.Set(key, 0)
+		}
+		c.Incr(3, 1)
+		expected = []cache.Pair[int, int]{{Key: 3, Value: 1}}
+		if got := c.TopK(1); !slices.Equal(got, expected) {
+			t.Errorf("round %d: Expected %v after reset, but got %v", round, expected, got)
+		}
+		c.Incr(3, -1)
+	}
+}